@@ -2,10 +2,12 @@ package aws
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"gobase-lambda/log"
+	"gobase-lambda/utils"
 )
 
 type DynamoDb struct {
@@ -17,6 +19,16 @@ type DynamoDb struct {
 
 var defaultDynamoDbClient *dynamodb.DynamoDB
 
+// GetTableName returns the stage prefixed table name, like GetSNSARN does for topics.
+func GetTableName(tableName string) string {
+	prefix := utils.Getenv("stage", "dev")
+	systemPefix := utils.Getenv("tablePrefix", "")
+	if systemPefix != "" {
+		prefix = fmt.Sprintf("%v_%v", prefix, systemPefix)
+	}
+	return fmt.Sprintf("%v_%v", prefix, tableName)
+}
+
 func GetAWSDynamoDbClient(awsSession *session.Session) *dynamodb.DynamoDB {
 	return dynamodb.New(awsSession)
 }
@@ -0,0 +1,151 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"gobase-lambda/log"
+	"gobase-lambda/utils"
+)
+
+// PutEvents limits, the size of an entry is computed like EventBridge does.
+const (
+	MaxEventBridgeBatchEntries = 10
+	MaxEventBridgeBatchSize    = 256 * 1024
+	eventBridgeTimeSize        = 14
+)
+
+type EventBridge struct {
+	_      struct{}
+	Client *eventbridge.EventBridge
+	Log    *log.Log
+	Ctx    context.Context
+}
+
+// EventBridgeEntry is a single event of PutEvents, Detail is marshalled to JSON unless it is a string.
+// The default event bus is used when EventBusName is empty.
+type EventBridgeEntry struct {
+	EventBusName string
+	Source       string
+	DetailType   string
+	Detail       interface{}
+	Resources    []string
+	Time         *time.Time
+}
+
+var defaultEventBridgeClient *eventbridge.EventBridge
+
+func GetDefaultEventBridgeClient(ctx context.Context) *EventBridge {
+	if defaultEventBridgeClient == nil {
+		defaultEventBridgeClient = GetAWSEventBridgeClient(defaultAWSSession)
+	}
+	return GetEventBridgeClient(ctx, defaultEventBridgeClient)
+}
+
+func GetAWSEventBridgeClient(awsSession *session.Session) *eventbridge.EventBridge {
+	client := eventbridge.New(awsSession)
+	return client
+}
+
+func GetEventBridgeClient(ctx context.Context, client *eventbridge.EventBridge) *EventBridge {
+	return &EventBridge{Client: client, Log: log.GetDefaultLogger(), Ctx: ctx}
+}
+
+// GetEventBusName returns the stage prefixed event bus name, like GetSNSARN does for topics.
+func GetEventBusName(busName string) string {
+	prefix := utils.Getenv("stage", "dev")
+	systemPefix := utils.Getenv("eventBusPrefix", "")
+	if systemPefix != "" {
+		prefix = fmt.Sprintf("%v_%v", prefix, systemPefix)
+	}
+	return fmt.Sprintf("%v_%v", prefix, busName)
+}
+
+// PutEvents sends the entries in batches of at most 10 entries and 256KB, nothing is sent when an
+// entry is larger than 256KB. The result entries are in the order of the entries, the ones with an
+// ErrorCode failed and the error reports how many did.
+func (e *EventBridge) PutEvents(entries []*EventBridgeEntry) ([]*eventbridge.PutEventsResultEntry, error) {
+	results := make([]*eventbridge.PutEventsResultEntry, 0, len(entries))
+	batch, batchSize, failedCount := make([]*eventbridge.PutEventsRequestEntry, 0, MaxEventBridgeBatchEntries), 0, int64(0)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		req := &eventbridge.PutEventsInput{Entries: batch}
+		e.Log.Debug("EventBridge put events request", req)
+		res, err := e.Client.PutEventsWithContext(e.Ctx, req)
+		if err != nil {
+			e.Log.Error("EventBridge put events error", err)
+			return err
+		}
+		e.Log.Debug("EventBridge put events response", res)
+		results = append(results, res.Entries...)
+		failedCount += aws.Int64Value(res.FailedEntryCount)
+		batch, batchSize = make([]*eventbridge.PutEventsRequestEntry, 0, MaxEventBridgeBatchEntries), 0
+		return nil
+	}
+	reqEntries, sizes := make([]*eventbridge.PutEventsRequestEntry, len(entries)), make([]int, len(entries))
+	for i, entry := range entries {
+		reqEntry, err := entry.requestEntry()
+		if err != nil {
+			return results, err
+		}
+		reqEntries[i], sizes[i] = reqEntry, eventBridgeEntrySize(reqEntry)
+		if sizes[i] > MaxEventBridgeBatchSize {
+			return results, fmt.Errorf("event %v of %v bytes exceeds the %v bytes limit", i, sizes[i], MaxEventBridgeBatchSize)
+		}
+	}
+	for i, reqEntry := range reqEntries {
+		if len(batch) == MaxEventBridgeBatchEntries || batchSize+sizes[i] > MaxEventBridgeBatchSize {
+			if err := flush(); err != nil {
+				return results, err
+			}
+		}
+		batch = append(batch, reqEntry)
+		batchSize += sizes[i]
+	}
+	if err := flush(); err != nil {
+		return results, err
+	}
+	if failedCount > 0 {
+		return results, fmt.Errorf("%v of %v events failed", failedCount, len(entries))
+	}
+	return results, nil
+}
+
+func (e *EventBridgeEntry) requestEntry() (*eventbridge.PutEventsRequestEntry, error) {
+	detail, ok := e.Detail.(string)
+	if !ok {
+		detailStr, err := utils.GetString(e.Detail)
+		if err != nil {
+			return nil, err
+		}
+		detail = *detailStr
+	}
+	reqEntry := &eventbridge.PutEventsRequestEntry{
+		Source:     aws.String(e.Source),
+		DetailType: aws.String(e.DetailType),
+		Detail:     aws.String(detail),
+		Resources:  aws.StringSlice(e.Resources),
+		Time:       e.Time,
+	}
+	if e.EventBusName != "" {
+		reqEntry.EventBusName = aws.String(e.EventBusName)
+	}
+	return reqEntry, nil
+}
+
+func eventBridgeEntrySize(entry *eventbridge.PutEventsRequestEntry) int {
+	size := len(aws.StringValue(entry.Source)) + len(aws.StringValue(entry.DetailType)) + len(aws.StringValue(entry.Detail))
+	if entry.Time != nil {
+		size += eventBridgeTimeSize
+	}
+	for _, resource := range entry.Resources {
+		size += len(aws.StringValue(resource))
+	}
+	return size
+}
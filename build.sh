@@ -6,4 +6,6 @@ cd ../sfnlambda
 go build -o ../../bin/lambdahandler
 cd ../cronlambda
 go build -o ../../bin/cronhandler
+cd ../eventlambda
+go build -o ../../bin/eventhandler
 cd ../../
\ No newline at end of file
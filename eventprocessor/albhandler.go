@@ -0,0 +1,108 @@
+package eventprocessor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// HandleALBRequest handles Application Load Balancer target group events with the same route map as
+// HandleAPIRequest. ALB events don't carry the resource template, so the routes are matched on the
+// raw path. When multi value headers are enabled on the target group the response is returned with
+// multi value headers as well.
+func (h *Handler) HandleALBRequest(ctx context.Context, request events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+	proxyRequest := newProxyRequestFromALB(&request)
+	res, err := h.processAPIRequest(ctx, proxyRequest, &request, EventALB)
+	albResponse := events.ALBTargetGroupResponse{
+		StatusCode:        res.StatusCode,
+		StatusDescription: fmt.Sprintf("%d %s", res.StatusCode, http.StatusText(res.StatusCode)),
+		Body:              res.Body,
+		IsBase64Encoded:   res.IsBase64Encoded,
+	}
+	if isMultiValueALBRequest(&request) {
+		albResponse.MultiValueHeaders = make(map[string][]string, len(res.Headers)+len(res.MultiValueHeaders))
+		for key, value := range res.Headers {
+			albResponse.MultiValueHeaders[key] = []string{value}
+		}
+		for key, values := range res.MultiValueHeaders {
+			albResponse.MultiValueHeaders[key] = append(albResponse.MultiValueHeaders[key], values...)
+		}
+	} else {
+		albResponse.Headers = make(map[string]string, len(res.Headers)+len(res.MultiValueHeaders))
+		for key, values := range res.MultiValueHeaders {
+			if len(values) > 0 {
+				albResponse.Headers[key] = values[len(values)-1]
+			}
+		}
+		for key, value := range res.Headers {
+			albResponse.Headers[key] = value
+		}
+	}
+	return albResponse, err
+}
+
+func isMultiValueALBRequest(request *events.ALBTargetGroupRequest) bool {
+	return request.MultiValueHeaders != nil || request.MultiValueQueryStringParameters != nil
+}
+
+// newProxyRequestFromALB converts the request to the REST API form, the load balancer passes the
+// query string parameters without decoding them.
+func newProxyRequestFromALB(request *events.ALBTargetGroupRequest) *events.APIGatewayProxyRequest {
+	headers := make(map[string]string, len(request.Headers)+len(request.MultiValueHeaders))
+	multiValueHeaders := make(map[string][]string, len(request.Headers)+len(request.MultiValueHeaders))
+	for key, values := range request.MultiValueHeaders {
+		multiValueHeaders[key] = values
+		if len(values) > 0 {
+			headers[key] = values[len(values)-1]
+		}
+	}
+	for key, value := range request.Headers {
+		headers[key] = value
+		if _, ok := multiValueHeaders[key]; !ok {
+			multiValueHeaders[key] = []string{value}
+		}
+	}
+	queryParams := make(map[string]string, len(request.QueryStringParameters)+len(request.MultiValueQueryStringParameters))
+	multiValueQueryParams := make(map[string][]string, len(request.QueryStringParameters)+len(request.MultiValueQueryStringParameters))
+	for key, values := range request.MultiValueQueryStringParameters {
+		key = unescapeQuery(key)
+		for _, value := range values {
+			multiValueQueryParams[key] = append(multiValueQueryParams[key], unescapeQuery(value))
+		}
+		if len(values) > 0 {
+			queryParams[key] = unescapeQuery(values[len(values)-1])
+		}
+	}
+	for key, value := range request.QueryStringParameters {
+		key, value = unescapeQuery(key), unescapeQuery(value)
+		queryParams[key] = value
+		if _, ok := multiValueQueryParams[key]; !ok {
+			multiValueQueryParams[key] = []string{value}
+		}
+	}
+	return &events.APIGatewayProxyRequest{
+		Path:                            request.Path,
+		HTTPMethod:                      request.HTTPMethod,
+		Headers:                         headers,
+		MultiValueHeaders:               multiValueHeaders,
+		QueryStringParameters:           queryParams,
+		MultiValueQueryStringParameters: multiValueQueryParams,
+		Body:                            request.Body,
+		IsBase64Encoded:                 request.IsBase64Encoded,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			HTTPMethod: request.HTTPMethod,
+			Path:       request.Path,
+		},
+	}
+}
+
+func unescapeQuery(value string) string {
+	unescaped, err := url.QueryUnescape(value)
+	if err != nil {
+		return value
+	}
+	return unescaped
+}
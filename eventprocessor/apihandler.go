@@ -1,117 +1,118 @@
 package eventprocessor
 
 import (
-	"bytes"
 	"context"
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"net/http"
-	"runtime/debug"
-	"strconv"
+	"reflect"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/fatih/structs"
-	"gobase-lambda/errornotification"
 	"gobase-lambda/log"
 	"gobase-lambda/utils"
 )
 
 type APIHandler func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error)
 
-func (h *Handler) HandleAPIRequest(ctx context.Context, request events.APIGatewayProxyRequest) (res events.APIGatewayProxyResponse, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			h.log.Error("Full Request", request)
-			h.log.Error("Panic Stack", string(debug.Stack()))
-			h.log.Error("Panic Recovery", r)
-			switch v := r.(type) {
-			case *utils.Error:
-				res.StatusCode = v.StatusCode
-				res.Body = fmt.Sprintf(`{"error":%v}`, v)
-			default:
-				res.StatusCode = http.StatusInternalServerError
-				res.Body = `{"error": "Error occurred please try after some time, if persist contact technical support"}`
-				notification := errornotification.ErrorNotifier{
-					StatusCode:   "500",
-					StackTrace:   string(debug.Stack()),
-					ErrorMessage: fmt.Sprintf("%s", r),
-					Log:          *h.log,
-				}
-				notification.PublishToSqs()
-				notification.PublishToSns()
-			}
-			h.log.Info("Response", res)
-		} else {
-			if err != nil {
-				h.log.Error("Full Request", request)
-				h.log.Error("API Error", err)
-				custErr, ok := err.(*utils.Error)
-				if ok {
-					res.StatusCode = custErr.StatusCode
-				} else {
-					res.StatusCode = http.StatusInternalServerError
-				}
-				res.Body = fmt.Sprintf(`{"error":%v}`, err)
-				h.log.Info("Response", res)
-			} else {
-				if res.StatusCode > 299 {
-					h.printReducedRequest(&request, false)
-					h.log.Info("Response", res)
-				} else {
-					h.log.Debug("Full Response", res)
-					str := res.Body
-					res.Body = "<<<redacted>>>"
-					h.log.Info("Response", res)
-					res.Body = str
-				}
+// APIContextHandler is an APIHandler receiving the ctx of the invocation, it is canceled before the
+// Lambda timeout and carries the logger and the correlation params, see GetLogger.
+type APIContextHandler func(ctx context.Context, headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error)
+
+func (h *Handler) HandleAPIRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return h.processAPIRequest(ctx, &request, &request, EventAPI)
+}
+
+// processAPIRequest runs the API pipeline on the REST API (payload v1) form of the request, other
+// HTTP triggers are converted to it. event is the original trigger event passed to the event processor.
+func (h *Handler) processAPIRequest(ctx context.Context, request *events.APIGatewayProxyRequest, event interface{}, eventType EventType) (events.APIGatewayProxyResponse, error) {
+	invocation := h.newInvocation(ctx, eventType, event)
+	invocation.APIRequest = request
+	result, err := h.invoke(invocation, func(invocation *Invocation) (interface{}, error) {
+		// the route is resolved on the request of the invocation, it is a copy when the handler runs
+		// before the Lambda timeout
+		request := invocation.APIRequest
+		eventProcessor := h.eventProcessorFunc(invocation.Ctx, invocation.Log, invocation.Event, invocation.EventType)
+		apiMap := h.withOpenAPIRoute(eventProcessor.GetAPIHandler())
+		if h.cors != nil && isPreflightRequest(request) {
+			resource, ok := resolveAPIResource(apiMap, request)
+			if ok && apiMap[resource][http.MethodOptions] == nil {
+				return h.cors.preflightResponse(request, apiMap[resource]), nil
 			}
 		}
-		err = nil
-	}()
-	h.setCorrelationParams(request.Headers)
-	h.log.Debug("Full Request", request)
-	eventProcessor := h.eventProcessorFunc(ctx, h.log, &request, EventAPI)
-	h.printReducedRequest(&request, true)
-	apiMap := eventProcessor.GetAPIHandler()
-	resource, method := request.Resource, request.HTTPMethod
-	methodMap, ok := apiMap[resource]
-	matchFound := false
-	var statusCode int
-	var response interface{}
-	if ok {
-		handler, ok := methodMap[method]
-		if ok {
-			var headers, queryParams, pathParams interface{}
-			var contentType string
-			matchFound = true
-			headers, queryParams, pathParams, contentType, jsonBody := extractAPIRequest(handler, &request)
-			if method == http.MethodGet {
-				statusCode, response, err = handler.ApiHandler(headers, pathParams, "", queryParams)
-			} else {
-				statusCode, response, err = handler.ApiHandler(headers, pathParams, jsonBody, nil)
-			}
-			if err != nil {
-				return
+		handler := resolveAPIRoute(apiMap, request)
+		if handler == nil {
+			errorMessage := fmt.Sprintf("path not found %v, %v", request.Path, request.HTTPMethod)
+			if request.Resource != "" {
+				errorMessage = fmt.Sprintf("path not found %v, %v", request.Resource, request.HTTPMethod)
 			}
-			resObj, ok := response.(events.APIGatewayProxyResponse)
-			if ok {
-				res = resObj
-			} else {
-				res.StatusCode = statusCode
-				res.Body, res.IsBase64Encoded = ProcessResponse(contentType, response)
-			}
-			return
+			invocation.Log.Alert(errorMessage, apiMap)
+			panic(utils.NewHTTPNotFoundError(errorMessage, nil))
 		}
+		invocation.API = handler
+		principal, principalErr := h.resolvePrincipal(request)
+		authorizeRoute(handler, principal, principalErr)
+		invocation.Principal = principal
+		return chainMiddleware(handler.Middleware, invokeAPI)(invocation)
+	})
+	res := proxyResponse(invocation, result, err)
+	if compression := h.compressionConfig(invocation.API); compression != nil {
+		compression.compress(request, &res)
+	}
+	if h.cors != nil {
+		h.cors.addHeaders(request, &res)
+	}
+	return res, nil
+}
 
+func invokeAPI(invocation *Invocation) (interface{}, error) {
+	var res events.APIGatewayProxyResponse
+	call := extractAPIRequest(invocation.API, invocation.APIRequest)
+	call.principal = invocation.Principal
+	call.ctx = invocation.Ctx
+	statusCode, response, err := invocation.API.call(call)
+	if err != nil {
+		return nil, err
 	}
-	if !matchFound {
-		errorMessage := fmt.Sprintf("path not found %v, %v", resource, method)
-		h.log.Alert(errorMessage, apiMap)
-		panic(utils.NewHTTPNotFoundError(errorMessage, nil))
+	switch v := response.(type) {
+	case events.APIGatewayProxyResponse:
+		res = v
+	case *FileResponse:
+		res = v.proxyResponse(statusCode)
+	case FileResponse:
+		res = v.proxyResponse(statusCode)
+	default:
+		if len(call.responseTypes) == 0 {
+			panicNotAcceptable(call.headers)
+		}
+		res = encodeResponse(call.responseTypes, response)
+		res.StatusCode = statusCode
 	}
-	return
+	return res, nil
+}
+
+// encodeResponse encodes the response with the first accepted media type whose codec can encode it, as
+// the XML codec can't encode maps for instance.
+func encodeResponse(responseTypes []string, response interface{}) (res events.APIGatewayProxyResponse) {
+	var encodeErr error
+	for _, responseType := range responseTypes {
+		codec, _ := GetCodec(responseType)
+		body, base64Encoding, err := encodeBody(codec, response)
+		if err == nil {
+			res.Body, res.IsBase64Encoded = body, base64Encoding
+			res.Headers = map[string]string{"Content-Type": responseType}
+			return res
+		}
+		if encodeErr == nil {
+			encodeErr = err
+		}
+	}
+	panic(utils.NewError(http.StatusInternalServerError, fmt.Sprintf("response processing failed : %v", encodeErr), "RESPONSE_ENCODING_FAILED", nil))
+}
+
+func panicNotAcceptable(headers map[string]string) {
+	accept := getHeader(headers, "Accept")
+	panic(utils.NewError(http.StatusNotAcceptable, fmt.Sprintf("none of the accepted media types %v is supported", accept), "NOT_ACCEPTABLE", nil))
 }
 
 // func ProcessRequestBody(requestBody string) (body []byte) {
@@ -122,135 +123,204 @@ func (h *Handler) HandleAPIRequest(ctx context.Context, request events.APIGatewa
 // 	return
 // }
 
+// ProcessResponse encodes the response with the codec of the media type, JSON is used for unknown
+// media types. The body of binary codecs is base64 encoded.
 func ProcessResponse(ct string, response interface{}) (resBody string, base64Encoding bool) {
-	switch contentType := strings.ToLower(ct); contentType {
-	case "application/x-protobuf":
-		u, err := response.([]byte)
-		if !err {
-			panic(utils.NewHTTPBadRequestError(fmt.Sprintf("response processing failed : %v", err), response))
-		}
-		resBody = base64.StdEncoding.EncodeToString(u)
-		base64Encoding = true
-	default:
-		bodyBlob := bytes.NewBuffer([]byte{})
-		jsonEncoder := json.NewEncoder(bodyBlob)
-		jsonEncoder.SetEscapeHTML(false)
-		jsonEncoder.Encode(response)
-		// bodyBlob, err := json.Marshal(response)
-		// if err != nil {
-		// 	return
-		// }
-		resBody = string(bodyBlob.String())
-		base64Encoding = false
+	codec, ok := GetCodec(parseMediaType(ct))
+	if !ok {
+		codec = jsonCodec{}
 	}
-	return
+	resBody, base64Encoding, err := encodeBody(codec, response)
+	if err != nil {
+		panic(utils.NewError(http.StatusInternalServerError, fmt.Sprintf("response processing failed : %v", err), "RESPONSE_ENCODING_FAILED", nil))
+	}
+	return resBody, base64Encoding
+}
+
+func encodeBody(codec Codec, response interface{}) (string, bool, error) {
+	bodyBlob, err := codec.Encode(response)
+	if err != nil {
+		return "", false, err
+	}
+	if codec.IsBinary() {
+		return base64.StdEncoding.EncodeToString(bodyBlob), true, nil
+	}
+	return string(bodyBlob), false, nil
 }
 
 type API struct {
-	Resource    string
-	Method      string
-	ApiHandler  APIHandler
-	Body        interface{}
-	QueryParams interface{}
-	PathParams  interface{}
+	Resource   string
+	Method     string
+	ApiHandler APIHandler
+	// ApiContextHandler is used instead of ApiHandler when it is set.
+	ApiContextHandler APIContextHandler
+	Body              interface{}
+	QueryParams       interface{}
+	PathParams        interface{}
+	Middleware        []Middleware
+	// Scopes are all required and Groups are alternatives, a route declaring any of them answers 401
+	// without a principal and 403 when the principal lacks them.
+	Scopes []string
+	Groups []string
+	// Multipart limits the files of multipart/form-data bodies, the Lambda payload limit applies when nil.
+	Multipart *MultipartLimits
+	// Compression overrides the compression set on the Handler for the route.
+	Compression *CompressionConfig
+	// Summary and Tags describe the route in the OpenAPI document.
+	Summary      string
+	Tags         []string
+	invoke       func(call *apiCall) (int, interface{}, error)
+	responseType reflect.Type
 }
 
-func extractAPIRequest(apiMap *API, request *events.APIGatewayProxyRequest) (headers interface{}, queryParams interface{}, pathParams interface{}, contentType string, jsonBody string) {
-	logger := log.GetDefaultLogger()
-	contentType, notExists := request.Headers["Content-Type"]
-	if notExists {
-		contentType = "application/json"
+// apiCall holds the values extracted from a request for a single handler invocation. body,
+// queryParams and pathParams are fresh instances of the types registered on the API, or the raw
+// request values when no type is registered.
+type apiCall struct {
+	headers       map[string]string
+	body          interface{}
+	queryParams   interface{}
+	pathParams    interface{}
+	contentType   string
+	responseTypes []string
+	jsonBody      string
+	request       *events.APIGatewayProxyRequest
+	principal     *Principal
+	ctx           context.Context
+}
+
+func (a *API) call(call *apiCall) (int, interface{}, error) {
+	if a.invoke != nil {
+		return a.invoke(call)
 	}
-	headers = request.Headers
-	if request.HTTPMethod == http.MethodGet {
-		queryParams = getQueryStringParams(apiMap, request, logger)
-	} else {
-		jsonBody = request.Body
+	jsonBody := call.jsonBody
+	if call.request.HTTPMethod == http.MethodGet {
+		jsonBody = ""
 	}
-	pathParams = getPathParams(apiMap, request, logger)
-	return
+	if a.ApiContextHandler != nil {
+		return a.ApiContextHandler(call.ctx, call.headers, call.pathParams, jsonBody, call.queryParams)
+	}
+	return a.ApiHandler(call.headers, call.pathParams, jsonBody, call.queryParams)
 }
 
-func getBody(apiMap *API, request *events.APIGatewayProxyRequest, logger *log.Log, contentType string) (body interface{}) {
-	body = request.Body
-	if apiMap.Body != nil && request.Body != "" {
-		switch ct := strings.ToLower(contentType); ct {
-		case "application/x-protobuf":
-			body, err := base64.StdEncoding.DecodeString(apiMap.Body.(string))
-			if err != nil {
-				panic(utils.NewHTTPBadRequestError(fmt.Sprintf("body unmarshal failed : %v", err), request.Body))
-			}
-			return body
-		default:
-			err := json.Unmarshal([]byte(request.Body), &apiMap.Body)
-			if err != nil {
-				panic(utils.NewHTTPBadRequestError(fmt.Sprintf("body unmarshal failed : %v", err), request.Body))
-			}
-			body = apiMap.Body
+// getHeader returns the value of the header, header names are matched case insensitively as HTTP
+// APIs and function URLs lower case them.
+func getHeader(headers map[string]string, name string) string {
+	if value, ok := headers[name]; ok {
+		return value
+	}
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
 		}
 	}
+	return ""
+}
+
+func extractAPIRequest(apiMap *API, request *events.APIGatewayProxyRequest) *apiCall {
+	logger := log.GetDefaultLogger()
+	call := &apiCall{headers: request.Headers, request: request, contentType: MediaTypeJSON}
+	if contentType := getHeader(request.Headers, "Content-Type"); contentType != "" {
+		call.contentType = parseMediaType(contentType)
+	}
+	call.responseTypes = getResponseTypes(request, call.contentType)
+	if len(call.responseTypes) == 0 && apiMap.encodesResponse() {
+		panicNotAcceptable(request.Headers)
+	}
+	call.queryParams = getQueryStringParams(apiMap, request, logger)
+	if request.HTTPMethod != http.MethodGet {
+		body := getRequestBody(request)
+		call.jsonBody = string(body)
+		call.body = getBody(apiMap, request, body, call.contentType)
+	}
+	call.pathParams = getPathParams(apiMap, request, logger)
+	validateAPIRequest(call)
+	return call
+}
+
+// getResponseTypes negotiates the response media types from the Accept header. Without a preference
+// the response is encoded like the request, except for forms which are answered with JSON. It is empty
+// when no codec is accepted.
+func getResponseTypes(request *events.APIGatewayProxyRequest, contentType string) []string {
+	fallback := MediaTypeJSON
+	if _, ok := GetCodec(contentType); ok && contentType != MediaTypeForm {
+		fallback = contentType
+	}
+	accept := getHeader(request.Headers, "Accept")
+	return negotiateMediaTypes(accept, fallback)
+}
+
+// encodesResponse reports whether the responses of the route are always encoded with a codec, so that
+// a request accepting none of them is rejected before the handler runs. Handlers which are not typed
+// with Route and the ones returning a FileResponse or a proxy response are checked on their response.
+func (a *API) encodesResponse() bool {
+	if a.responseType == nil {
+		return false
+	}
+	responseType := a.responseType
+	for responseType.Kind() == reflect.Ptr {
+		responseType = responseType.Elem()
+	}
+	return responseType != fileResponseType && responseType != proxyResponseType && responseType.Kind() != reflect.Interface
+}
+
+// getRequestBody returns the raw request body, decoding it when API Gateway passes it base64 encoded.
+func getRequestBody(request *events.APIGatewayProxyRequest) []byte {
+	if !request.IsBase64Encoded {
+		return []byte(request.Body)
+	}
+	body, err := base64.StdEncoding.DecodeString(request.Body)
+	if err != nil {
+		panic(utils.NewHTTPBadRequestError(fmt.Sprintf("body decoding failed : %v", err), nil))
+	}
+	return body
+}
+
+// newInstance returns a new zero value of the type registered on the API, so that values are never
+// shared between requests.
+func newInstance(registered interface{}) interface{} {
+	registeredType := reflect.TypeOf(registered)
+	if registeredType.Kind() == reflect.Ptr {
+		return reflect.New(registeredType.Elem()).Interface()
+	}
+	return reflect.New(registeredType).Interface()
+}
+
+func getBody(apiMap *API, request *events.APIGatewayProxyRequest, requestBody []byte, contentType string) (body interface{}) {
+	body = string(requestBody)
+	if apiMap.Body == nil {
+		return
+	}
+	body = newInstance(apiMap.Body)
+	if len(requestBody) == 0 {
+		return
+	}
+	if contentType == MediaTypeMultipart {
+		return getMultipartBody(apiMap, request, requestBody, body)
+	}
+	if isMultipartBody(reflect.TypeOf(body)) {
+		panic(utils.NewError(http.StatusUnsupportedMediaType, fmt.Sprintf("content type %v is not supported, files are uploaded as %v", contentType, MediaTypeMultipart), "UNSUPPORTED_MEDIA_TYPE", nil))
+	}
+	codec, ok := GetCodec(contentType)
+	if !ok {
+		panic(utils.NewError(http.StatusUnsupportedMediaType, fmt.Sprintf("content type %v is not supported", contentType), "UNSUPPORTED_MEDIA_TYPE", nil))
+	}
+	err := codec.Decode(requestBody, body)
+	if err != nil {
+		panic(utils.NewHTTPBadRequestError(fmt.Sprintf("body unmarshal failed : %v", err), request.Body))
+	}
 	return
 }
 
 func getQueryStringParams(apiMap *API, request *events.APIGatewayProxyRequest, logger *log.Log) (queryStringParams interface{}) {
 	queryStringParams = request.MultiValueQueryStringParameters
 	if apiMap.QueryParams != nil {
-		queryParamMap := structs.Map(apiMap.QueryParams)
-		queryParamValues := make(map[string]interface{}, len(queryParamMap))
-		for key, value := range queryParamMap {
-			switch v := value.(type) {
-			case string:
-				reqValue, ok := request.QueryStringParameters[key]
-				if ok {
-					queryParamValues[key] = reqValue
-				}
-			case []string:
-				reqValue, ok := request.MultiValueQueryStringParameters[key]
-				if ok {
-					queryParamValues[key] = reqValue
-				}
-			case int:
-				reqValue, ok := request.QueryStringParameters[key]
-				if ok {
-					intVal, err := strconv.Atoi(reqValue)
-					if err != nil {
-						panic(utils.NewHTTPBadRequestError(fmt.Sprintf("invalid value for int, %v-%v", key, reqValue), request.QueryStringParameters))
-					}
-					queryParamValues[key] = intVal
-				}
-			case []int:
-				reqValue, ok := request.MultiValueQueryStringParameters[key]
-				if ok {
-					intList := make([]int, len(reqValue))
-					for i, val := range reqValue {
-						intVal, err := strconv.Atoi(val)
-						if err != nil {
-							panic(utils.NewHTTPBadRequestError(fmt.Sprintf("invalid value for int, %v-%v", key, reqValue), request.MultiValueQueryStringParameters))
-						}
-						intList[i] = intVal
-					}
-					queryParamValues[key] = intList
-				}
-			case bool:
-				reqValue, ok := request.QueryStringParameters[key]
-				if ok {
-					var flag bool
-					if reqValue == "true" {
-						flag = true
-					} else if reqValue == "false" {
-						flag = false
-					} else {
-						panic(utils.NewHTTPBadRequestError(fmt.Sprintf("invalid value for bool %v - %v", key, v), request.QueryStringParameters))
-					}
-					queryParamValues[key] = flag
-				}
-			default:
-				panic(utils.NewHTTPBadRequestError(fmt.Sprintf("%T is not supported for query string params", v), apiMap.QueryParams))
-			}
+		queryStringParams = newInstance(apiMap.QueryParams)
+		fieldErrors := bindQueryParams(reflect.ValueOf(queryStringParams).Elem(), request.QueryStringParameters, request.MultiValueQueryStringParameters)
+		if len(fieldErrors) > 0 {
+			logger.Error("Query string params binding failed", fieldErrors)
+			panic(utils.NewError(http.StatusBadRequest, "request validation failed", "VALIDATION_FAILED", fieldErrors))
 		}
-		jsonString, _ := json.Marshal(queryParamValues)
-		json.Unmarshal(jsonString, apiMap.QueryParams)
-		queryStringParams = apiMap.QueryParams
 	}
 	return
 }
@@ -258,15 +328,12 @@ func getQueryStringParams(apiMap *API, request *events.APIGatewayProxyRequest, l
 func getPathParams(apiMap *API, request *events.APIGatewayProxyRequest, logger *log.Log) (pathParams interface{}) {
 	pathParams = request.PathParameters
 	if apiMap.PathParams != nil {
-		blob, err := json.Marshal(request.PathParameters)
-		if err != nil {
-			panic(fmt.Errorf("path param unmarshal failed : %v", err))
-		}
-		err = json.Unmarshal(blob, apiMap.PathParams)
-		if err != nil {
-			panic(fmt.Errorf("path param unmarshal failed : %v", err))
+		pathParams = newInstance(apiMap.PathParams)
+		fieldErrors := bindPathParams(reflect.ValueOf(pathParams).Elem(), request.PathParameters)
+		if len(fieldErrors) > 0 {
+			logger.Error("Path params binding failed", fieldErrors)
+			panic(utils.NewError(http.StatusBadRequest, "request validation failed", "VALIDATION_FAILED", fieldErrors))
 		}
-		pathParams = apiMap.PathParams
 	}
 	return
 }
@@ -0,0 +1,103 @@
+package eventprocessor
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// resolveAPIRoute finds the API for the request. Requests without a resource template of the route map
+// (function URLs, load balancers, $default or ANY /{proxy+} routes) are matched on the raw path, and
+// the resource and the path parameters of the request are filled from the matched template.
+func resolveAPIRoute(apiMap map[string]map[string]*API, request *events.APIGatewayProxyRequest) *API {
+	if methodMap, ok := apiMap[request.Resource]; ok && request.Resource != "" {
+		return methodMap[request.HTTPMethod]
+	}
+	var matchedAPI *API
+	var matchedParams map[string]string
+	matchedScore := -1
+	for resource, methodMap := range apiMap {
+		api, ok := methodMap[request.HTTPMethod]
+		if !ok {
+			continue
+		}
+		pathParams, score, ok := matchResource(resource, request.Path)
+		if ok && score > matchedScore {
+			request.Resource = resource
+			matchedAPI, matchedParams, matchedScore = api, pathParams, score
+		}
+	}
+	if matchedAPI != nil {
+		if request.PathParameters == nil {
+			request.PathParameters = make(map[string]string, len(matchedParams))
+		}
+		for key, value := range matchedParams {
+			request.PathParameters[key] = value
+		}
+	}
+	return matchedAPI
+}
+
+// resolveAPIResource finds the resource template of the request regardless of the method.
+func resolveAPIResource(apiMap map[string]map[string]*API, request *events.APIGatewayProxyRequest) (string, bool) {
+	if _, ok := apiMap[request.Resource]; ok && request.Resource != "" {
+		return request.Resource, true
+	}
+	matchedResource, matchedScore := "", -1
+	for resource := range apiMap {
+		_, score, ok := matchResource(resource, request.Path)
+		if ok && score > matchedScore {
+			matchedResource, matchedScore = resource, score
+		}
+	}
+	return matchedResource, matchedScore >= 0
+}
+
+// matchResource matches the path against a resource template like /customer/{customerId}/{proxy+}.
+// The score is the number of static segments matched, so that static routes win over parameters.
+func matchResource(resource, path string) (pathParams map[string]string, score int, ok bool) {
+	resourceSegments := splitPath(resource)
+	pathSegments := splitPath(path)
+	pathParams = make(map[string]string)
+	for i, segment := range resourceSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "+}") {
+			if i >= len(pathSegments) {
+				return nil, 0, false
+			}
+			pathParams[segment[1:len(segment)-2]] = unescapePathSegment(strings.Join(pathSegments[i:], "/"))
+			return pathParams, score, true
+		}
+		if i >= len(pathSegments) {
+			return nil, 0, false
+		}
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			pathParams[segment[1:len(segment)-1]] = unescapePathSegment(pathSegments[i])
+			continue
+		}
+		if segment != pathSegments[i] {
+			return nil, 0, false
+		}
+		score++
+	}
+	if len(resourceSegments) != len(pathSegments) {
+		return nil, 0, false
+	}
+	return pathParams, score, true
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return []string{}
+	}
+	return strings.Split(path, "/")
+}
+
+func unescapePathSegment(segment string) string {
+	value, err := url.PathUnescape(segment)
+	if err != nil {
+		return segment
+	}
+	return value
+}
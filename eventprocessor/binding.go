@@ -0,0 +1,202 @@
+package eventprocessor
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gobase-lambda/utils"
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// bindQueryParams sets the fields of the struct from the query string, the parameter name is taken
+// from the structs tag, then the json tag. Missing parameters take the value of the default tag, which
+// is split on commas for slices.
+//
+//	Status   []string         `structs:"status" json:"status"`
+//	From     time.Time        `structs:"from" json:"from"`         // RFC3339 or 2006-01-02
+//	Timeout  time.Duration    `structs:"timeout" json:"timeout" default:"30s"`
+//	Amount   *decimal.Decimal `structs:"amount" json:"amount"`
+//	PageSize int              `structs:"pageSize" json:"pageSize" default:"20"`
+//
+// Besides strings, bools and numbers, any type implementing encoding.TextUnmarshaler is supported,
+// pointers are left nil when the parameter is not present.
+func bindQueryParams(target reflect.Value, queryParams map[string]string, multiValueQueryParams map[string][]string) []*FieldError {
+	fieldErrors := make([]*FieldError, 0)
+	targetType := target.Type()
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		if !field.IsExported() || field.Type == filePartType || field.Type == filePartsType {
+			continue
+		}
+		key := queryParamName(field)
+		if key == "-" {
+			continue
+		}
+		values, ok := multiValueQueryParams[key]
+		if !ok || len(values) == 0 {
+			if value, ok := queryParams[key]; ok {
+				values = []string{value}
+			} else if defaultValue, ok := field.Tag.Lookup("default"); ok {
+				values = defaultParamValues(field.Type, defaultValue)
+			} else {
+				continue
+			}
+		}
+		err := setParamValue(target.Field(i), values)
+		if err != nil {
+			fieldErrors = append(fieldErrors, &FieldError{Field: key, In: paramInQuery, Reason: err.Error()})
+		}
+	}
+	return fieldErrors
+}
+
+// bindPathParams sets the fields of the struct from the path parameters like bindQueryParams does, the
+// parameter name is taken from the json tag, then the structs tag, and matched regardless of case.
+func bindPathParams(target reflect.Value, pathParams map[string]string) []*FieldError {
+	fieldErrors := make([]*FieldError, 0)
+	targetType := target.Type()
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		key := fieldName(field)
+		if !field.IsExported() || key == "-" {
+			continue
+		}
+		value, ok := pathParams[key]
+		if !ok {
+			for name, v := range pathParams {
+				if strings.EqualFold(name, key) {
+					value, ok = v, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		err := setParamValue(target.Field(i), []string{value})
+		if err != nil {
+			fieldErrors = append(fieldErrors, &FieldError{Field: key, In: paramInPath, Reason: err.Error()})
+		}
+	}
+	return fieldErrors
+}
+
+func defaultParamValues(fieldType reflect.Type, defaultValue string) []string {
+	if fieldType.Kind() == reflect.Slice && !isScalarParamType(fieldType) {
+		return strings.Split(defaultValue, ",")
+	}
+	return []string{defaultValue}
+}
+
+func queryParamName(field reflect.StructField) string {
+	for _, tagName := range []string{"structs", "json"} {
+		if tag, ok := field.Tag.Lookup(tagName); ok {
+			name := strings.Split(tag, ",")[0]
+			if name != "" {
+				return name
+			}
+		}
+	}
+	return field.Name
+}
+
+func setParamValue(value reflect.Value, values []string) error {
+	if value.Kind() == reflect.Slice && !isScalarParamType(value.Type()) {
+		slice := reflect.MakeSlice(value.Type(), len(values), len(values))
+		for i, v := range values {
+			err := setScalarParamValue(slice.Index(i), v)
+			if err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	}
+	return setScalarParamValue(value, values[len(values)-1])
+}
+
+// isScalarParamType reports whether the type is bound from a single parameter value.
+func isScalarParamType(valueType reflect.Type) bool {
+	return valueType == timeType || reflect.PointerTo(valueType).Implements(textUnmarshalerType)
+}
+
+func setScalarParamValue(value reflect.Value, raw string) error {
+	if value.Kind() == reflect.Ptr {
+		elem := reflect.New(value.Type().Elem())
+		err := setScalarParamValue(elem.Elem(), raw)
+		if err != nil {
+			return err
+		}
+		value.Set(elem)
+		return nil
+	}
+	switch value.Type() {
+	case timeType:
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			parsed, err = time.Parse(dateLayout, raw)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for time, expected RFC3339 or %v", dateLayout)
+		}
+		value.Set(reflect.ValueOf(parsed))
+		return nil
+	case durationType:
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid value for duration")
+		}
+		value.SetInt(int64(parsed))
+		return nil
+	}
+	if unmarshaler, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		err := unmarshaler.UnmarshalText([]byte(raw))
+		if err != nil {
+			return fmt.Errorf("invalid value for %v", value.Type())
+		}
+		return nil
+	}
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid value for bool")
+		}
+		value.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid value for %v", value.Kind())
+		}
+		value.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(raw, 10, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid value for %v", value.Kind())
+		}
+		value.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid value for %v", value.Kind())
+		}
+		value.SetFloat(parsed)
+	default:
+		panic(utils.NewError(http.StatusInternalServerError, fmt.Sprintf("%v is not supported for params", value.Type()), "UNSUPPORTED_PARAM_TYPE", nil))
+	}
+	return nil
+}
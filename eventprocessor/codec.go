@@ -0,0 +1,474 @@
+package eventprocessor
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	MediaTypeJSON     = "application/json"
+	MediaTypeXML      = "application/xml"
+	MediaTypeTextXML  = "text/xml"
+	MediaTypeCSV      = "text/csv"
+	MediaTypeForm     = "application/x-www-form-urlencoded"
+	MediaTypeMsgpack  = "application/msgpack"
+	MediaTypeXMsgpack = "application/x-msgpack"
+	MediaTypeProtobuf = "application/x-protobuf"
+	MediaTypeProto    = "application/protobuf"
+)
+
+// Codec decodes request bodies and encodes responses of a media type. The body of binary codecs is
+// base64 encoded in the Lambda response.
+type Codec interface {
+	Decode(data []byte, value interface{}) error
+	Encode(value interface{}) ([]byte, error)
+	IsBinary() bool
+}
+
+var codecs = map[string]Codec{
+	MediaTypeJSON:     jsonCodec{},
+	MediaTypeXML:      xmlCodec{},
+	MediaTypeTextXML:  xmlCodec{},
+	MediaTypeCSV:      csvCodec{},
+	MediaTypeForm:     formCodec{},
+	MediaTypeMsgpack:  msgpackCodec{},
+	MediaTypeXMsgpack: msgpackCodec{},
+	MediaTypeProtobuf: protobufCodec{},
+	MediaTypeProto:    protobufCodec{},
+}
+
+// RegisterCodec adds or replaces the codec of a media type, it is meant to be called at init as the
+// registry is shared by all the handlers.
+func RegisterCodec(mediaType string, codec Codec) {
+	codecs[strings.ToLower(mediaType)] = codec
+}
+
+func GetCodec(mediaType string) (Codec, bool) {
+	codec, ok := codecs[strings.ToLower(mediaType)]
+	return codec, ok
+}
+
+// parseMediaType returns the media type of a Content-Type header without its parameters.
+func parseMediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.TrimSpace(strings.Split(contentType, ";")[0])
+	}
+	return strings.ToLower(mediaType)
+}
+
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+type acceptedMediaType struct {
+	mediaType   string
+	quality     float64
+	specificity int
+	position    int
+}
+
+// negotiateMediaTypes returns the media types of the codecs accepted by the Accept header, the most
+// preferred first. A media type gets the q-value of the most specific range matching it, ties are won
+// by the fallback, then by the most specific range and then by its position in the header. The
+// fallback is used for a missing header, it is the only one returned then.
+func negotiateMediaTypes(accept, fallback string) []string {
+	if strings.TrimSpace(accept) == "" {
+		return []string{fallback}
+	}
+	ranges := make([]acceptRange, 0)
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: strings.ToLower(mediaType), quality: quality})
+	}
+	mediaTypes := make([]string, 0, len(codecs))
+	for mediaType := range codecs {
+		if mediaType != fallback {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	sort.Strings(mediaTypes)
+	accepted := make([]acceptedMediaType, 0)
+	for _, mediaType := range append([]string{fallback}, mediaTypes...) {
+		match := acceptedMediaType{mediaType: mediaType}
+		for i, r := range ranges {
+			specificity := acceptRangeSpecificity(r.mediaType, mediaType)
+			if specificity > match.specificity {
+				match.quality, match.specificity, match.position = r.quality, specificity, i
+			}
+		}
+		if match.specificity > 0 && match.quality > 0 {
+			accepted = append(accepted, match)
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool {
+		switch {
+		case accepted[i].quality != accepted[j].quality:
+			return accepted[i].quality > accepted[j].quality
+		case accepted[i].mediaType == fallback || accepted[j].mediaType == fallback:
+			return accepted[i].mediaType == fallback
+		case accepted[i].specificity != accepted[j].specificity:
+			return accepted[i].specificity > accepted[j].specificity
+		}
+		return accepted[i].position < accepted[j].position
+	})
+	responseTypes := make([]string, len(accepted))
+	for i, a := range accepted {
+		responseTypes[i] = a.mediaType
+	}
+	return responseTypes
+}
+
+// acceptRangeSpecificity returns 3 when the range is the media type, 2 for its type/* range, 1 for */*
+// and 0 when the range doesn't match it.
+func acceptRangeSpecificity(acceptRange, mediaType string) int {
+	switch {
+	case acceptRange == mediaType:
+		return 3
+	case acceptRange == "*/*":
+		return 1
+	case strings.HasSuffix(acceptRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(acceptRange, "*")):
+		return 2
+	}
+	return 0
+}
+
+// jsonCodec uses protojson for proto.Message values, so that protobuf routes serve JSON clients too.
+type jsonCodec struct{}
+
+func (jsonCodec) Decode(data []byte, value interface{}) error {
+	if message, ok := value.(proto.Message); ok {
+		return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, message)
+	}
+	return json.Unmarshal(data, value)
+}
+
+func (jsonCodec) Encode(value interface{}) ([]byte, error) {
+	if message, ok := value.(proto.Message); ok {
+		return protojson.Marshal(message)
+	}
+	bodyBlob := bytes.NewBuffer([]byte{})
+	jsonEncoder := json.NewEncoder(bodyBlob)
+	jsonEncoder.SetEscapeHTML(false)
+	err := jsonEncoder.Encode(value)
+	return bodyBlob.Bytes(), err
+}
+
+func (jsonCodec) IsBinary() bool {
+	return false
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) Decode(data []byte, value interface{}) error {
+	return xml.Unmarshal(data, value)
+}
+
+func (xmlCodec) Encode(value interface{}) ([]byte, error) {
+	return xml.Marshal(value)
+}
+
+func (xmlCodec) IsBinary() bool {
+	return false
+}
+
+// msgpackCodec uses the json tags of the structs, so that the same types serve both media types.
+type msgpackCodec struct{}
+
+func (msgpackCodec) Decode(data []byte, value interface{}) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(value)
+}
+
+func (msgpackCodec) Encode(value interface{}) ([]byte, error) {
+	bodyBlob := bytes.NewBuffer([]byte{})
+	encoder := msgpack.NewEncoder(bodyBlob)
+	encoder.SetCustomStructTag("json")
+	err := encoder.Encode(value)
+	return bodyBlob.Bytes(), err
+}
+
+func (msgpackCodec) IsBinary() bool {
+	return true
+}
+
+// protobufCodec works with proto.Message bodies and responses, already serialized messages can be
+// passed as []byte.
+type protobufCodec struct{}
+
+func (protobufCodec) Decode(data []byte, value interface{}) error {
+	switch target := value.(type) {
+	case proto.Message:
+		return proto.Unmarshal(data, target)
+	case *[]byte:
+		*target = data
+		return nil
+	}
+	return fmt.Errorf("%T is not a proto.Message", value)
+}
+
+func (protobufCodec) Encode(value interface{}) ([]byte, error) {
+	switch source := value.(type) {
+	case proto.Message:
+		return proto.Marshal(source)
+	case []byte:
+		return source, nil
+	}
+	return nil, fmt.Errorf("%T is not a proto.Message", value)
+}
+
+func (protobufCodec) IsBinary() bool {
+	return true
+}
+
+// formCodec binds form fields like query string params, see bindQueryParams.
+type formCodec struct{}
+
+func (formCodec) Decode(data []byte, value interface{}) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	switch target := value.(type) {
+	case *url.Values:
+		*target = values
+		return nil
+	case *map[string][]string:
+		*target = values
+		return nil
+	case *map[string]string:
+		*target = make(map[string]string, len(values))
+		for key := range values {
+			(*target)[key] = values.Get(key)
+		}
+		return nil
+	}
+	target := reflect.ValueOf(value)
+	if target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%T can't be decoded from a form", value)
+	}
+	fieldErrors := bindQueryParams(target.Elem(), map[string]string{}, values)
+	if len(fieldErrors) > 0 {
+		return fmt.Errorf("%v %v", fieldErrors[0].Field, fieldErrors[0].Reason)
+	}
+	return nil
+}
+
+func (formCodec) Encode(value interface{}) ([]byte, error) {
+	values := url.Values{}
+	switch source := value.(type) {
+	case url.Values:
+		values = source
+	case map[string][]string:
+		values = source
+	case map[string]string:
+		for key, v := range source {
+			values.Set(key, v)
+		}
+	default:
+		structValue := reflect.Indirect(reflect.ValueOf(value))
+		if structValue.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%T can't be encoded as a form", value)
+		}
+		for i := 0; i < structValue.NumField(); i++ {
+			field := structValue.Type().Field(i)
+			key := queryParamName(field)
+			if !field.IsExported() || key == "-" {
+				continue
+			}
+			fieldValue := structValue.Field(i)
+			if fieldValue.Kind() == reflect.Slice && !isScalarParamType(fieldValue.Type()) {
+				for j := 0; j < fieldValue.Len(); j++ {
+					values.Add(key, formatParamValue(fieldValue.Index(j)))
+				}
+				continue
+			}
+			if fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil() {
+				continue
+			}
+			values.Set(key, formatParamValue(fieldValue))
+		}
+	}
+	return []byte(values.Encode()), nil
+}
+
+func (formCodec) IsBinary() bool {
+	return false
+}
+
+// formatParamValue is the reverse of setScalarParamValue.
+func formatParamValue(value reflect.Value) string {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	switch v := value.Interface().(type) {
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case time.Duration:
+		return v.String()
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		if err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprintf("%v", value.Interface())
+}
+
+// csvCodec decodes into a slice of structs, the header row is matched to the csv tag, then the json
+// tag of the fields. [][]string is supported for files without a known layout.
+type csvCodec struct{}
+
+func csvFieldName(field reflect.StructField) string {
+	if tag, ok := field.Tag.Lookup("csv"); ok {
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			return name
+		}
+	}
+	return fieldName(field)
+}
+
+func (csvCodec) Decode(data []byte, value interface{}) error {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return err
+	}
+	if target, ok := value.(*[][]string); ok {
+		*target = records
+		return nil
+	}
+	target := reflect.ValueOf(value)
+	if target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("%T can't be decoded from csv", value)
+	}
+	sliceType := target.Elem().Type()
+	elemType := sliceType.Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("%T can't be decoded from csv", value)
+	}
+	slice := reflect.MakeSlice(sliceType, 0, len(records))
+	if len(records) == 0 {
+		target.Elem().Set(slice)
+		return nil
+	}
+	columns := make([]int, len(records[0]))
+	for i, header := range records[0] {
+		columns[i] = -1
+		for j := 0; j < structType.NumField(); j++ {
+			field := structType.Field(j)
+			if field.IsExported() && strings.EqualFold(csvFieldName(field), strings.TrimSpace(header)) {
+				columns[i] = j
+				break
+			}
+		}
+	}
+	for line, record := range records[1:] {
+		elem := reflect.New(structType)
+		for i, raw := range record {
+			if i >= len(columns) || columns[i] < 0 || raw == "" {
+				continue
+			}
+			if err := setParamValue(elem.Elem().Field(columns[i]), strings.Split(raw, ",")); err != nil {
+				return fmt.Errorf("line %v column %v : %v", line+2, records[0][i], err)
+			}
+		}
+		if elemType.Kind() == reflect.Ptr {
+			slice = reflect.Append(slice, elem)
+		} else {
+			slice = reflect.Append(slice, elem.Elem())
+		}
+	}
+	target.Elem().Set(slice)
+	return nil
+}
+
+func (csvCodec) Encode(value interface{}) ([]byte, error) {
+	records, ok := value.([][]string)
+	if !ok {
+		source := reflect.Indirect(reflect.ValueOf(value))
+		if source.Kind() == reflect.Struct {
+			source = reflect.Append(reflect.MakeSlice(reflect.SliceOf(source.Type()), 0, 1), source)
+		}
+		if source.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("%T can't be encoded as csv", value)
+		}
+		structType := source.Type().Elem()
+		if structType.Kind() == reflect.Ptr {
+			structType = structType.Elem()
+		}
+		if structType.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%T can't be encoded as csv", value)
+		}
+		fields, header := make([]int, 0), make([]string, 0)
+		for i := 0; i < structType.NumField(); i++ {
+			field := structType.Field(i)
+			if name := csvFieldName(field); field.IsExported() && name != "-" {
+				fields, header = append(fields, i), append(header, name)
+			}
+		}
+		records = append(records, header)
+		for i := 0; i < source.Len(); i++ {
+			elem := reflect.Indirect(source.Index(i))
+			record := make([]string, len(fields))
+			if elem.IsValid() {
+				for j, field := range fields {
+					record[j] = formatCSVValue(elem.Field(field))
+				}
+			}
+			records = append(records, record)
+		}
+	}
+	bodyBlob := bytes.NewBuffer([]byte{})
+	writer := csv.NewWriter(bodyBlob)
+	err := writer.WriteAll(records)
+	return bodyBlob.Bytes(), err
+}
+
+// formatCSVValue joins slices with commas, they are split again by Decode.
+func formatCSVValue(value reflect.Value) string {
+	if value.Kind() != reflect.Slice || isScalarParamType(value.Type()) {
+		return formatParamValue(value)
+	}
+	values := make([]string, value.Len())
+	for i := range values {
+		values[i] = formatParamValue(value.Index(i))
+	}
+	return strings.Join(values, ",")
+}
+
+func (csvCodec) IsBinary() bool {
+	return false
+}
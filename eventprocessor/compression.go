@@ -0,0 +1,188 @@
+package eventprocessor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+)
+
+const (
+	headerAcceptEncoding  = "Accept-Encoding"
+	headerContentEncoding = "Content-Encoding"
+	headerContentLength   = "Content-Length"
+)
+
+const defaultCompressionMinSize = 1024
+
+// CompressionConfig enables the compression of the API responses whose body is at least MinSize bytes,
+// with the first of Encodings preferred by the Accept-Encoding header of the client. Encodings default
+// to brotli then gzip and MinSize to 1KB. Compressed bodies are sent base64 encoded.
+//
+//	handler.SetCompression(&eventprocessor.CompressionConfig{MinSize: 4 * 1024})
+type CompressionConfig struct {
+	MinSize   int
+	Encodings []string
+	// Disabled turns the compression off, set it on API.Compression to exclude a route.
+	Disabled bool
+}
+
+// SetCompression enables response compression for all the routes, API.Compression overrides it per route.
+func (h *Handler) SetCompression(config *CompressionConfig) {
+	h.compression = config
+}
+
+func (h *Handler) compressionConfig(api *API) *CompressionConfig {
+	if api != nil && api.Compression != nil {
+		return api.Compression
+	}
+	return h.compression
+}
+
+// compress replaces the body of the response with its compressed form, it is left as is when the client
+// doesn't accept any of the encodings or the compression doesn't make it smaller.
+func (c *CompressionConfig) compress(request *events.APIGatewayProxyRequest, res *events.APIGatewayProxyResponse) {
+	if c.Disabled || res.Body == "" || res.StatusCode == http.StatusNoContent || res.StatusCode == http.StatusNotModified {
+		return
+	}
+	if getHeader(res.Headers, headerContentEncoding) != "" || !isCompressible(getHeader(res.Headers, "Content-Type")) {
+		return
+	}
+	if res.Headers == nil {
+		res.Headers = make(map[string]string)
+	}
+	addVary(res.Headers, headerAcceptEncoding)
+	body := []byte(res.Body)
+	if res.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(res.Body)
+		if err != nil {
+			return
+		}
+		body = decoded
+	}
+	minSize := c.MinSize
+	if minSize <= 0 {
+		minSize = defaultCompressionMinSize
+	}
+	if len(body) < minSize {
+		return
+	}
+	encoding, ok := c.negotiateEncoding(getHeader(request.Headers, headerAcceptEncoding))
+	if !ok {
+		return
+	}
+	compressed, err := compressBody(encoding, body)
+	if err != nil || len(compressed) >= len(body) {
+		return
+	}
+	res.Headers[headerContentEncoding] = encoding
+	if getHeader(res.Headers, headerContentLength) != "" {
+		res.Headers[headerContentLength] = strconv.Itoa(len(compressed))
+	}
+	res.Body = base64.StdEncoding.EncodeToString(compressed)
+	res.IsBase64Encoded = true
+}
+
+// negotiateEncoding picks the encoding with the highest q-value in the Accept-Encoding header, ties go
+// to the order of the configured encodings.
+func (c *CompressionConfig) negotiateEncoding(acceptEncoding string) (string, bool) {
+	encodings := c.Encodings
+	if len(encodings) == 0 {
+		encodings = []string{EncodingBrotli, EncodingGzip}
+	}
+	qValues := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		qValues[name] = quality
+	}
+	selected, selectedQ := "", 0.0
+	for _, encoding := range encodings {
+		q, ok := qValues[encoding]
+		if !ok {
+			q = qValues["*"]
+		}
+		if q > selectedQ {
+			selected, selectedQ = encoding, q
+		}
+	}
+	return selected, selected != ""
+}
+
+func compressBody(encoding string, body []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case EncodingBrotli:
+		writer = brotli.NewWriterLevel(&buffer, brotli.DefaultCompression)
+	case EncodingGzip:
+		writer = gzip.NewWriter(&buffer)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %v", encoding)
+	}
+	if _, err := writer.Write(body); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// isCompressible excludes the media types which are already compressed.
+func isCompressible(contentType string) bool {
+	mediaType := parseMediaType(contentType)
+	switch {
+	case mediaType == "image/svg+xml":
+		return true
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "audio/"):
+		return false
+	}
+	switch mediaType {
+	case "application/zip", "application/gzip", "application/x-gzip", "application/x-brotli", "application/x-7z-compressed", "application/x-rar-compressed":
+		return false
+	}
+	return true
+}
+
+// addVary adds the header name to the Vary header of the response.
+func addVary(headers map[string]string, name string) {
+	key, vary := headerVary, ""
+	for k, v := range headers {
+		if strings.EqualFold(k, headerVary) {
+			key, vary = k, v
+			break
+		}
+	}
+	for _, value := range strings.Split(vary, ",") {
+		if strings.EqualFold(strings.TrimSpace(value), name) {
+			return
+		}
+	}
+	if vary == "" {
+		headers[key] = name
+	} else {
+		headers[key] = vary + ", " + name
+	}
+}
@@ -14,14 +14,23 @@ type EventProcessor interface {
 	GetAPIHandler() map[string]map[string]*API
 	GetSNSHandler() map[string]map[string]*SNS
 	GetCronHandler() map[string]*CronInvocation
+	GetLambdaHandler() map[string]*LambdaInvocation
 	GetSQSEventHandler() map[string]*SQS
 	GetS3EventHandler() map[string]map[string]map[string]*S3Trigger
+	GetEventBridgeHandler() map[string]map[string][]*EventBridge
+	GetDynamoDBStreamHandler() map[string]map[string]*DynamoDBStream
 }
 
 const (
-	EventAPI  EventType = "API"
-	EventCRON EventType = "CRON"
-	EventSNS  EventType = "SNS"
-	EventSQS  EventType = "SQS"
-	EventS3   EventType = "S3"
+	EventAPI         EventType = "API"
+	EventHTTPAPI     EventType = "HTTP_API"
+	EventFunctionURL EventType = "FUNCTION_URL"
+	EventALB         EventType = "ALB"
+	EventCRON        EventType = "CRON"
+	EventLambda      EventType = "LAMBDA"
+	EventSNS         EventType = "SNS"
+	EventSQS         EventType = "SQS"
+	EventS3          EventType = "S3"
+	EventEventBridge EventType = "EVENTBRIDGE"
+	EventDynamoDB    EventType = "DYNAMODB"
 )
@@ -0,0 +1,50 @@
+package eventprocessor
+
+import (
+	"context"
+	"time"
+
+	"gobase-lambda/log"
+)
+
+type contextKey string
+
+const (
+	loggerContextKey        contextKey = "logger"
+	correlationContextKey   contextKey = "correlationParams"
+	scheduledTimeContextKey contextKey = "scheduledTime"
+)
+
+// defaultTimeoutMargin leaves time to log and send the timeout response before the Lambda is stopped.
+const defaultTimeoutMargin = 500 * time.Millisecond
+
+// SetTimeoutMargin sets how long before the Lambda timeout the ctx of the handlers is canceled and the
+// invocation answered with a 504 error, 500ms by default.
+func (h *Handler) SetTimeoutMargin(margin time.Duration) {
+	h.timeoutMargin = margin
+}
+
+// GetLogger returns the logger of the invocation from the ctx passed to the handlers, or the default
+// logger for other contexts.
+func GetLogger(ctx context.Context) *log.Log {
+	if logger, ok := ctx.Value(loggerContextKey).(*log.Log); ok {
+		return logger
+	}
+	return log.GetDefaultLogger()
+}
+
+// GetCorrelationParams returns the correlation params of the invocation from the ctx passed to the
+// handlers, like x-correlation-id.
+func GetCorrelationParams(ctx context.Context) map[string]string {
+	if correlationParams, ok := ctx.Value(correlationContextKey).(map[string]string); ok {
+		return correlationParams
+	}
+	return map[string]string{}
+}
+
+// GetScheduledTime returns the time a cron invocation was scheduled for, it is false for other
+// invocations and for cron events without a scheduled time.
+func GetScheduledTime(ctx context.Context) (time.Time, bool) {
+	scheduledTime, ok := ctx.Value(scheduledTimeContextKey).(time.Time)
+	return scheduledTime, ok
+}
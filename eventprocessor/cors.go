@@ -0,0 +1,157 @@
+package eventprocessor
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// CORSConfig enables CORS for the API routes. AllowedOrigins can contain "*" or wildcard patterns like
+// https://*.example.com, "*" can't be combined with AllowCredentials as it would let any site make
+// credentialed requests. When AllowedMethods is empty the preflight allows the methods registered for
+// the resource, and when AllowedHeaders is empty it allows the headers requested by the browser.
+//
+//	handler.SetCORS(&eventprocessor.CORSConfig{
+//		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.com"},
+//		AllowCredentials: true,
+//		MaxAge:           600,
+//	})
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int
+}
+
+const (
+	headerOrigin                     = "Origin"
+	headerVary                       = "Vary"
+	headerAllowOrigin                = "Access-Control-Allow-Origin"
+	headerAllowMethods               = "Access-Control-Allow-Methods"
+	headerAllowHeaders               = "Access-Control-Allow-Headers"
+	headerAllowCredentials           = "Access-Control-Allow-Credentials"
+	headerExposeHeaders              = "Access-Control-Expose-Headers"
+	headerMaxAge                     = "Access-Control-Max-Age"
+	headerAccessControlRequestMethod = "Access-Control-Request-Method"
+	headerAccessControlRequestHeader = "Access-Control-Request-Headers"
+)
+
+// SetCORS enables CORS handling for HandleAPIRequest and the other HTTP handlers. Preflight requests
+// are answered for every registered resource which doesn't register its own OPTIONS route, and the
+// CORS headers are added to all the responses, error responses included. It panics when the config
+// is invalid, see Validate.
+func (h *Handler) SetCORS(config *CORSConfig) {
+	if err := config.Validate(); err != nil {
+		h.log.Alert("Invalid CORS config", err.Error())
+		panic(err)
+	}
+	h.cors = config
+}
+
+// Validate rejects the "*" origin with AllowCredentials, the allowed origins have to be listed then.
+func (c *CORSConfig) Validate() error {
+	if !c.AllowCredentials {
+		return nil
+	}
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return fmt.Errorf("cors: the * origin can't be used with AllowCredentials, list the allowed origins instead")
+		}
+	}
+	return nil
+}
+
+func isPreflightRequest(request *events.APIGatewayProxyRequest) bool {
+	return request.HTTPMethod == http.MethodOptions &&
+		getHeader(request.Headers, headerOrigin) != "" &&
+		getHeader(request.Headers, headerAccessControlRequestMethod) != ""
+}
+
+// preflightResponse answers the preflight request for a registered resource, a disallowed origin gets
+// the response without CORS headers so that the browser rejects the request.
+func (c *CORSConfig) preflightResponse(request *events.APIGatewayProxyRequest, methodMap map[string]*API) events.APIGatewayProxyResponse {
+	res := events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent, Headers: map[string]string{}}
+	allowOrigin, ok := c.allowOrigin(getHeader(request.Headers, headerOrigin))
+	res.Headers[headerVary] = headerOrigin
+	if !ok {
+		return res
+	}
+	res.Headers[headerAllowOrigin] = allowOrigin
+	methods := c.AllowedMethods
+	if len(methods) == 0 {
+		methods = make([]string, 0, len(methodMap))
+		for method := range methodMap {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+	}
+	res.Headers[headerAllowMethods] = strings.Join(methods, ", ")
+	if len(c.AllowedHeaders) > 0 {
+		res.Headers[headerAllowHeaders] = strings.Join(c.AllowedHeaders, ", ")
+	} else if requestHeaders := getHeader(request.Headers, headerAccessControlRequestHeader); requestHeaders != "" {
+		res.Headers[headerAllowHeaders] = requestHeaders
+	}
+	if c.AllowCredentials {
+		res.Headers[headerAllowCredentials] = "true"
+	}
+	if c.MaxAge > 0 {
+		res.Headers[headerMaxAge] = strconv.Itoa(c.MaxAge)
+	}
+	return res
+}
+
+// addHeaders adds the CORS headers to the response of an actual request, headers already set by the
+// handler are kept.
+func (c *CORSConfig) addHeaders(request *events.APIGatewayProxyRequest, res *events.APIGatewayProxyResponse) {
+	origin := getHeader(request.Headers, headerOrigin)
+	if origin == "" {
+		return
+	}
+	if res.Headers == nil {
+		res.Headers = make(map[string]string)
+	}
+	if getHeader(res.Headers, headerAllowOrigin) != "" {
+		return
+	}
+	addVary(res.Headers, headerOrigin)
+	allowOrigin, ok := c.allowOrigin(origin)
+	if !ok {
+		return
+	}
+	res.Headers[headerAllowOrigin] = allowOrigin
+	if c.AllowCredentials {
+		res.Headers[headerAllowCredentials] = "true"
+	}
+	if len(c.ExposedHeaders) > 0 {
+		res.Headers[headerExposeHeaders] = strings.Join(c.ExposedHeaders, ", ")
+	}
+}
+
+// allowOrigin returns the value of Access-Control-Allow-Origin for the origin, "*" is sent as is and
+// patterns are answered with the origin.
+func (c *CORSConfig) allowOrigin(origin string) (string, bool) {
+	if origin == "" {
+		return "", false
+	}
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return "*", true
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin, true
+		}
+		if strings.Contains(allowed, "*") {
+			if matched, _ := path.Match(strings.ToLower(allowed), strings.ToLower(origin)); matched {
+				return origin, true
+			}
+		}
+	}
+	return "", false
+}
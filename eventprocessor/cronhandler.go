@@ -4,83 +4,145 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
-	"runtime/debug"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"gobase-lambda/utils"
 )
 
+const (
+	detailTypeScheduledEvent = "Scheduled Event"
+	cronDetailActionField    = "actionName"
+	cronDetailPayloadField   = "payload"
+)
+
+// CronEvent is the custom cron payload, EventBridge rule events are accepted as well and kept in
+// ScheduledEvent. EventBridge Scheduler delivers the input of the schedule as is, so schedules send the
+// custom payload with "scheduledTime": "<aws.scheduler.scheduled-time>" to pass the scheduled time.
+// ScheduledTime can be set by input transformers as well, for rule events it is the time of the event.
 type CronEvent struct {
-	IsCron     bool        `json:"isCron"`
-	ActionName string      `json:"actionName"`
-	Payload    interface{} `json:"payload"`
+	IsCron         bool                    `json:"isCron"`
+	ActionName     string                  `json:"actionName"`
+	Payload        interface{}             `json:"payload"`
+	ScheduledTime  time.Time               `json:"scheduledTime"`
+	ScheduledEvent *events.CloudWatchEvent `json:"-"`
+}
+
+func (e *CronEvent) UnmarshalJSON(data []byte) error {
+	type cronEvent CronEvent
+	if err := json.Unmarshal(data, (*cronEvent)(e)); err != nil {
+		return err
+	}
+	if e.IsCron {
+		return nil
+	}
+	scheduledEvent := &events.CloudWatchEvent{}
+	if err := json.Unmarshal(data, scheduledEvent); err != nil {
+		return err
+	}
+	if scheduledEvent.DetailType == detailTypeScheduledEvent {
+		e.ScheduledEvent = scheduledEvent
+		e.ScheduledTime = scheduledEvent.Time
+	}
+	return nil
 }
 
 type CronHandler func(payload interface{}) (statusCode int, response interface{}, err error)
 
+// CronContextHandler is a CronHandler receiving the ctx of the invocation.
+type CronContextHandler func(ctx context.Context, payload interface{}) (statusCode int, response interface{}, err error)
+
+// CronInvocation maps an action to its handler, CronContextHandlerFunc is used instead of
+// CronHandlerFunc when it is set.
 type CronInvocation struct {
-	CronHandlerFunc CronHandler
+	CronHandlerFunc        CronHandler
+	CronContextHandlerFunc CronContextHandler
 }
 
-func (h *Handler) HandleCronInvocation(ctx context.Context, request CronEvent) (res events.APIGatewayProxyResponse, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			h.log.Error("Full Request", request)
-			h.log.Error("Panic Stack", string(debug.Stack()))
-			h.log.Error("Panic Recovery", r)
-			switch v := r.(type) {
-			case *utils.Error:
-				res.StatusCode = v.StatusCode
-				res.Body = fmt.Sprintf(`{"error":%v}`, v)
-			default:
-				res.StatusCode = http.StatusInternalServerError
-				res.Body = `{"error": "Error occurred please try after some time, if persist contact technical support"}`
-			}
-		} else {
-			if err != nil {
-				h.log.Error("Cron Error", err)
-				custErr, ok := err.(*utils.Error)
-				if ok {
-					res.StatusCode = custErr.StatusCode
-				} else {
-					res.StatusCode = http.StatusInternalServerError
-				}
-				res.Body = fmt.Sprintf(`{"error":%v}`, err)
-			}
+// HandleCronInvocation runs the action of the cron event. Rule events are mapped by the actionName field
+// of the detail, then by the rule name with or without the stage prefix, the payload field of the
+// detail or the whole detail is passed to the handler. The scheduled time is available with
+// GetScheduledTime.
+func (h *Handler) HandleCronInvocation(ctx context.Context, request CronEvent) (events.APIGatewayProxyResponse, error) {
+	if !request.ScheduledTime.IsZero() {
+		ctx = context.WithValue(ctx, scheduledTimeContextKey, request.ScheduledTime)
+	}
+	invocation := h.newInvocation(ctx, EventCRON, &request)
+	result, err := h.invoke(invocation, func(invocation *Invocation) (interface{}, error) {
+		eventProcessor := h.eventProcessorFunc(invocation.Ctx, invocation.Log, invocation.Event, EventCRON)
+		cronHandlerMap := eventProcessor.GetCronHandler()
+		actionNames, payload := []string{request.ActionName}, request.Payload
+		if request.ScheduledEvent != nil {
+			actionNames, payload = scheduledAction(request.ScheduledEvent)
 		}
-		h.log.Info("Response", res)
-	}()
-	h.setCorrelationParams(map[string]string{})
-	h.log.Debug("Full Request", request)
-
-	eventProcessor := h.eventProcessorFunc(ctx, h.log, &request, EventCRON)
-	cronHandlerMap := eventProcessor.GetCronHandler()
-	actionName, payload := request.ActionName, request.Payload
-	actionHandler, ok := cronHandlerMap[actionName]
-	var response interface{}
-	var statusCode int
-	if ok {
-		statusCode, response, err = actionHandler.CronHandlerFunc(payload)
+		actionHandler := findCronHandler(cronHandlerMap, actionNames)
+		if actionHandler == nil {
+			errorMessage := fmt.Sprintf("action %v is not mapped", strings.Join(actionNames, ", "))
+			h.log.Alert(errorMessage, cronHandlerMap)
+			panic(utils.NewHTTPNotFoundError(errorMessage, nil))
+		}
+		statusCode, response, err := actionHandler.call(invocation.Ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		if resObj, ok := response.(events.APIGatewayProxyResponse); ok {
+			return resObj, nil
+		}
+		bodyBlob, err := json.Marshal(response)
 		if err != nil {
-			return
+			return nil, err
 		}
-		resObj, ok := response.(events.APIGatewayProxyResponse)
-		if ok {
-			res = resObj
-		} else {
-			res.StatusCode = statusCode
-			var bodyBlob []byte
-			bodyBlob, err = json.Marshal(response)
-			if err != nil {
-				return
-			}
-			res.Body = string(bodyBlob)
+		return events.APIGatewayProxyResponse{StatusCode: statusCode, Body: string(bodyBlob)}, nil
+	})
+	return triggerResponse(invocation, result, err)
+}
+
+func (c *CronInvocation) call(ctx context.Context, payload interface{}) (int, interface{}, error) {
+	if c.CronContextHandlerFunc != nil {
+		return c.CronContextHandlerFunc(ctx, payload)
+	}
+	return c.CronHandlerFunc(payload)
+}
+
+// scheduledAction returns the action names to look up for the rule event and the payload of the handler.
+func scheduledAction(event *events.CloudWatchEvent) (actionNames []string, payload interface{}) {
+	var detail interface{}
+	if len(event.Detail) > 0 {
+		if err := json.Unmarshal(event.Detail, &detail); err != nil {
+			panic(utils.NewHTTPBadRequestError(fmt.Sprintf("detail unmarshal failed : %v", err), string(event.Detail)))
+		}
+	}
+	payload = detail
+	if detailMap, ok := detail.(map[string]interface{}); ok {
+		if actionName, ok := detailMap[cronDetailActionField].(string); ok && actionName != "" {
+			actionNames = append(actionNames, actionName)
+		}
+		if detailPayload, ok := detailMap[cronDetailPayloadField]; ok {
+			payload = detailPayload
+		} else if len(detailMap) == 0 {
+			payload = nil
 		}
-	} else {
-		errorMessage := fmt.Sprintf("action %v is not mapped", actionName)
-		h.log.Alert(errorMessage, cronHandlerMap)
-		panic(utils.NewHTTPNotFoundError(errorMessage, nil))
+	}
+	for _, resource := range event.Resources {
+		resourceSplit := strings.Split(resource, "/")
+		actionNames = append(actionNames, resourceSplit[len(resourceSplit)-1])
 	}
 	return
 }
+
+func findCronHandler(cronHandlerMap map[string]*CronInvocation, actionNames []string) *CronInvocation {
+	stage := utils.Getenv("stage", "")
+	for _, actionName := range actionNames {
+		if actionHandler, ok := cronHandlerMap[actionName]; ok {
+			return actionHandler
+		}
+		if stage == "" || !strings.HasPrefix(actionName, stage+"_") {
+			continue
+		}
+		if actionHandler, ok := cronHandlerMap[strings.TrimPrefix(actionName, stage+"_")]; ok {
+			return actionHandler
+		}
+	}
+	return nil
+}
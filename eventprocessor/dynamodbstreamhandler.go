@@ -0,0 +1,137 @@
+package eventprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"gobase-lambda/aws"
+	"gobase-lambda/utils"
+)
+
+// Stream record event names.
+const (
+	DynamoDBInsert = "INSERT"
+	DynamoDBModify = "MODIFY"
+	DynamoDBRemove = "REMOVE"
+)
+
+// DynamoDBStreamHandler receives the stream record with NewImage and OldImage unmarshalled into new
+// instances of the Image type of the registration, or map[string]interface{} when it is nil. An image
+// which is not in the record, like the NewImage of a REMOVE, is nil.
+type DynamoDBStreamHandler func(ctx context.Context, record *events.DynamoDBEventRecord, newImage, oldImage interface{}) error
+
+// DynamoDBStream is the handler of a table event, GetDynamoDBStreamHandler maps them by table name
+// without the stage prefix and by INSERT, MODIFY or REMOVE. The events of a table without a handler
+// are skipped.
+type DynamoDBStream struct {
+	// Image is the type the images are unmarshalled into with dynamodbattribute, json tags are supported.
+	Image                 interface{}
+	DynamoDBStreamHandler DynamoDBStreamHandler
+}
+
+// HandleDynamoDBStreamRequest processes the records in order and reports the failed one through
+// BatchItemFailures, so the event source mapping has to be configured with ReportBatchItemFailures.
+// Processing stops at the first failure as Lambda retries the stream from that record. A failure
+// outside the records fails the whole batch.
+func (h *Handler) HandleDynamoDBStreamRequest(ctx context.Context, request events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+	invocation := h.newInvocation(ctx, EventDynamoDB, &request)
+	result, err := h.invoke(invocation, func(invocation *Invocation) (interface{}, error) {
+		eventProcessor := h.eventProcessorFunc(invocation.Ctx, invocation.Log, invocation.Event, EventDynamoDB)
+		streamMap := eventProcessor.GetDynamoDBStreamHandler()
+		res := events.DynamoDBEventResponse{BatchItemFailures: make([]events.DynamoDBBatchItemFailure, 0)}
+		for i := range request.Records {
+			record := &request.Records[i]
+			recordErr := h.processDynamoDBRecord(invocation.Ctx, streamMap, record)
+			if recordErr != nil {
+				res.BatchItemFailures = append(res.BatchItemFailures, events.DynamoDBBatchItemFailure{ItemIdentifier: record.Change.SequenceNumber})
+				break
+			}
+		}
+		return res, nil
+	})
+	if res, ok := result.(events.DynamoDBEventResponse); ok && err == nil {
+		return res, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("%v", proxyResponse(invocation, result, nil).Body)
+	}
+	return events.DynamoDBEventResponse{}, err
+}
+
+func (h *Handler) processDynamoDBRecord(ctx context.Context, streamMap map[string]map[string]*DynamoDBStream, record *events.DynamoDBEventRecord) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = h.recordPanicError(r, record, true)
+		} else if err != nil {
+			h.log.Error("DynamoDB Record", record)
+			h.log.Error("DynamoDB Record Error", err)
+		}
+	}()
+	tableName := getStreamTableName(record.EventSourceArn)
+	eventMap, ok := findStreamHandlers(streamMap, tableName)
+	if !ok {
+		errorMessage := fmt.Sprintf("table %v is not mapped", tableName)
+		h.log.Alert(errorMessage, streamMap)
+		panic(utils.NewHTTPNotFoundError(errorMessage, nil))
+	}
+	// the stream carries every event of the table, the ones without a handler are skipped rather than
+	// failed as a failure would block the shard
+	handler := eventMap[record.EventName]
+	if handler == nil {
+		h.log.Info("DynamoDB Record Skipped", fmt.Sprintf("table %v event %v is not mapped", tableName, record.EventName))
+		return nil
+	}
+	newImage := unmarshalStreamImage(handler, record.Change.NewImage)
+	oldImage := unmarshalStreamImage(handler, record.Change.OldImage)
+	return handler.DynamoDBStreamHandler(ctx, record, newImage, oldImage)
+}
+
+// getStreamTableName returns the table of a stream ARN, like
+// arn:aws:dynamodb:ap-south-1:123456789012:table/dev_CUSTOMER/stream/2024-01-01T00:00:00.000.
+func getStreamTableName(streamArn string) string {
+	arnSplit := strings.Split(streamArn, "/")
+	if len(arnSplit) < 2 {
+		panic(utils.NewHTTPBadRequestError("Unknown DynamoDB stream", streamArn))
+	}
+	return arnSplit[1]
+}
+
+// findStreamHandlers matches the table names of the map prefixed like aws.GetTableName does.
+func findStreamHandlers(streamMap map[string]map[string]*DynamoDBStream, tableName string) (map[string]*DynamoDBStream, bool) {
+	for key, value := range streamMap {
+		if strings.EqualFold(tableName, aws.GetTableName(key)) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func unmarshalStreamImage(handler *DynamoDBStream, image map[string]events.DynamoDBAttributeValue) interface{} {
+	if len(image) == 0 {
+		return nil
+	}
+	blob, err := json.Marshal(image)
+	if err != nil {
+		panic(fmt.Errorf("image marshal failed : %v", err))
+	}
+	item := make(map[string]*dynamodb.AttributeValue)
+	if err := json.Unmarshal(blob, &item); err != nil {
+		panic(fmt.Errorf("image unmarshal failed : %v", err))
+	}
+	var value interface{} = &map[string]interface{}{}
+	if handler.Image != nil {
+		value = newInstance(handler.Image)
+	}
+	if err := dynamodbattribute.UnmarshalMap(item, value); err != nil {
+		panic(utils.NewHTTPBadRequestError(fmt.Sprintf("image unmarshal failed : %v", err), string(blob)))
+	}
+	if handler.Image == nil {
+		return *value.(*map[string]interface{})
+	}
+	return value
+}
@@ -0,0 +1,152 @@
+package eventprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/log"
+	"gobase-lambda/utils"
+)
+
+const paramInDetail = "detail"
+
+// EventBridgeHandler receives the event and its detail, the detail is a new instance of the Detail type
+// of the registration or a map[string]interface{} when it is nil.
+type EventBridgeHandler func(ctx context.Context, event *events.CloudWatchEvent, detail interface{}) error
+
+// EventBridge is the handler of the events of a source and detail type. GetEventBridgeHandler maps
+// them by source and detail type, several registrations of the same pair are told apart by Match,
+// the first one matching the detail is used.
+//
+//	"orders": {"OrderPlaced": {
+//		{Match: map[string]interface{}{"$.channel": "web"}, Detail: &OrderPlaced{}, EventBridgeHandler: m.WebOrderPlaced},
+//		{Detail: &OrderPlaced{}, EventBridgeHandler: m.OrderPlaced},
+//	}}
+type EventBridge struct {
+	// Detail is decoded from the detail of the event and validated like API bodies.
+	Detail interface{}
+	// Match maps JSON paths of the detail, like $.order.items[0].sku, to the values they must be equal
+	// to, a nil value only requires the path to exist.
+	Match              map[string]interface{}
+	EventBridgeHandler EventBridgeHandler
+}
+
+func (h *Handler) HandleEventBridgeRequest(ctx context.Context, request events.CloudWatchEvent) (events.APIGatewayProxyResponse, error) {
+	invocation := h.newInvocation(ctx, EventEventBridge, &request)
+	result, err := h.invoke(invocation, func(invocation *Invocation) (interface{}, error) {
+		eventProcessor := h.eventProcessorFunc(invocation.Ctx, invocation.Log, invocation.Event, EventEventBridge)
+		eventBridgeMap := eventProcessor.GetEventBridgeHandler()
+		handler, detail := extractEventBridgeHandler(invocation.Log, eventBridgeMap, &request)
+		invocation.Log.Info("Source", request.Source)
+		invocation.Log.Info("Detail Type", request.DetailType)
+		err := handler.EventBridgeHandler(invocation.Ctx, &request, detail)
+		if err != nil {
+			return nil, err
+		}
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent}, nil
+	})
+	return triggerResponse(invocation, result, err)
+}
+
+func extractEventBridgeHandler(logger *log.Log, eventBridgeMap map[string]map[string][]*EventBridge, request *events.CloudWatchEvent) (*EventBridge, interface{}) {
+	handlers, ok := eventBridgeMap[request.Source][request.DetailType]
+	if !ok {
+		errorMessage := fmt.Sprintf("event %v %v is not mapped", request.Source, request.DetailType)
+		logger.Alert(errorMessage, eventBridgeMap)
+		panic(utils.NewHTTPNotFoundError(errorMessage, nil))
+	}
+	var detail interface{}
+	if len(request.Detail) > 0 {
+		if err := json.Unmarshal(request.Detail, &detail); err != nil {
+			panic(utils.NewHTTPBadRequestError(fmt.Sprintf("detail unmarshal failed : %v", err), string(request.Detail)))
+		}
+	}
+	for _, handler := range handlers {
+		if handler != nil && matchDetail(detail, handler.Match) {
+			return handler, decodeDetail(handler, request.Detail, detail)
+		}
+	}
+	errorMessage := fmt.Sprintf("event %v %v does not match any handler", request.Source, request.DetailType)
+	logger.Alert(errorMessage, detail)
+	panic(utils.NewHTTPNotFoundError(errorMessage, nil))
+}
+
+func decodeDetail(handler *EventBridge, rawDetail json.RawMessage, detail interface{}) interface{} {
+	if handler.Detail == nil {
+		return detail
+	}
+	typedDetail := newInstance(handler.Detail)
+	if len(rawDetail) > 0 {
+		if err := json.Unmarshal(rawDetail, typedDetail); err != nil {
+			panic(utils.NewHTTPBadRequestError(fmt.Sprintf("detail unmarshal failed : %v", err), string(rawDetail)))
+		}
+	}
+	if fieldErrors := Validate(typedDetail, paramInDetail); len(fieldErrors) > 0 {
+		panic(utils.NewError(http.StatusBadRequest, "detail validation failed", "VALIDATION_FAILED", fieldErrors))
+	}
+	return typedDetail
+}
+
+func matchDetail(detail interface{}, match map[string]interface{}) bool {
+	for path, expected := range match {
+		value, ok := jsonPathValue(detail, path)
+		if !ok {
+			return false
+		}
+		if expected != nil && !reflect.DeepEqual(value, normalizeJSON(expected)) {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonPathValue returns the value at a JSON path made of keys and array indexes, like $.a.b[0].c,
+// the leading $ is optional.
+func jsonPathValue(value interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return value, true
+	}
+	for _, segment := range strings.Split(strings.ReplaceAll(path, "[", ".["), ".") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, "[") && strings.HasSuffix(segment, "]") {
+			index, err := strconv.Atoi(segment[1 : len(segment)-1])
+			list, ok := value.([]interface{})
+			if err != nil || !ok || index < 0 || index >= len(list) {
+				return nil, false
+			}
+			value = list[index]
+			continue
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[segment]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// normalizeJSON converts the expected value of a match to the types produced by json.Unmarshal, so
+// that 5 matches the float64 5 of the detail.
+func normalizeJSON(value interface{}) interface{} {
+	blob, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(blob, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
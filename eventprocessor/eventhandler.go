@@ -0,0 +1,151 @@
+package eventprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/utils"
+)
+
+const (
+	eventSourceSNS      = "aws:sns"
+	eventSourceSQS      = "aws:sqs"
+	eventSourceS3       = "aws:s3"
+	eventSourceDynamoDB = "aws:dynamodb"
+)
+
+type eventProbe struct {
+	Version        string `json:"version"`
+	HTTPMethod     string `json:"httpMethod"`
+	Resource       string `json:"resource"`
+	RequestContext struct {
+		DomainName string `json:"domainName"`
+		ELB        struct {
+			TargetGroupArn string `json:"targetGroupArn"`
+		} `json:"elb"`
+		HTTP struct {
+			Method string `json:"method"`
+		} `json:"http"`
+	} `json:"requestContext"`
+	DetailType         string `json:"detail-type"`
+	Source             string `json:"source"`
+	IsCron             bool   `json:"isCron"`
+	IsLambdaInvocation bool   `json:"isLambdaInvocation"`
+	Records            []struct {
+		EventSource string `json:"eventSource"`
+	} `json:"Records"`
+}
+
+// HandleEvent is a single entry point for all the supported triggers. It detects the event type
+// from the payload shape and dispatches it to the respective Handle* method, so one binary can be
+// wired to API Gateway (REST and HTTP APIs), function URLs, load balancers, SNS, SQS, S3, DynamoDB
+// streams, cron, EventBridge events and schedules and direct invocations.
+func (h *Handler) HandleEvent(ctx context.Context, request json.RawMessage) (res interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.log.Error("Full Request", string(request))
+			h.log.Error("Event Error", r)
+			res, err = nil, utils.NewHTTPBadRequestError(fmt.Sprintf("%v", r), nil)
+		}
+	}()
+	eventType, request := detectEventType(request)
+	switch eventType {
+	case EventAPI:
+		var event events.APIGatewayProxyRequest
+		unmarshalEvent(request, &event)
+		return h.HandleAPIRequest(ctx, event)
+	case EventHTTPAPI:
+		var event events.APIGatewayV2HTTPRequest
+		unmarshalEvent(request, &event)
+		return h.HandleHTTPAPIRequest(ctx, event)
+	case EventFunctionURL:
+		var event events.LambdaFunctionURLRequest
+		unmarshalEvent(request, &event)
+		return h.HandleFunctionURLRequest(ctx, event)
+	case EventALB:
+		var event events.ALBTargetGroupRequest
+		unmarshalEvent(request, &event)
+		return h.HandleALBRequest(ctx, event)
+	case EventSNS:
+		var event events.SNSEvent
+		unmarshalEvent(request, &event)
+		return h.HandleSNSRequest(ctx, event)
+	case EventSQS:
+		var event events.SQSEvent
+		unmarshalEvent(request, &event)
+		if h.isSQSRecordMode(ctx, &event) {
+			return h.HandleSQSBatchRequest(ctx, event)
+		}
+		return h.HandleSQSRequest(ctx, event)
+	case EventS3:
+		var event events.S3Event
+		unmarshalEvent(request, &event)
+		return h.HandleS3TriggerRequest(ctx, event)
+	case EventCRON:
+		var event CronEvent
+		unmarshalEvent(request, &event)
+		return h.HandleCronInvocation(ctx, event)
+	case EventDynamoDB:
+		var event events.DynamoDBEvent
+		unmarshalEvent(request, &event)
+		return h.HandleDynamoDBStreamRequest(ctx, event)
+	case EventEventBridge:
+		var event events.CloudWatchEvent
+		unmarshalEvent(request, &event)
+		return h.HandleEventBridgeRequest(ctx, event)
+	case EventLambda:
+		return h.HandleLambdaInvocation(ctx, request)
+	}
+	h.log.Alert("Unsupported event", string(request))
+	return nil, utils.NewHTTPBadRequestError("unsupported event", nil)
+}
+
+func detectEventType(request json.RawMessage) (EventType, json.RawMessage) {
+	var jsonStr string
+	if json.Unmarshal(request, &jsonStr) == nil {
+		request = json.RawMessage(jsonStr)
+	}
+	probe := &eventProbe{}
+	if err := json.Unmarshal(request, probe); err != nil {
+		return "", request
+	}
+	switch {
+	case probe.HTTPMethod != "" && probe.Resource != "":
+		return EventAPI, request
+	case probe.HTTPMethod != "" && probe.RequestContext.ELB.TargetGroupArn != "":
+		return EventALB, request
+	case probe.Version == "2.0" && probe.RequestContext.HTTP.Method != "":
+		if strings.Contains(probe.RequestContext.DomainName, ".lambda-url.") {
+			return EventFunctionURL, request
+		}
+		return EventHTTPAPI, request
+	case probe.IsCron, probe.DetailType == detailTypeScheduledEvent:
+		return EventCRON, request
+	case probe.DetailType != "" && probe.Source != "":
+		return EventEventBridge, request
+	case probe.IsLambdaInvocation:
+		return EventLambda, request
+	case len(probe.Records) > 0:
+		switch probe.Records[0].EventSource {
+		case eventSourceSNS:
+			return EventSNS, request
+		case eventSourceSQS:
+			return EventSQS, request
+		case eventSourceS3:
+			return EventS3, request
+		case eventSourceDynamoDB:
+			return EventDynamoDB, request
+		}
+	}
+	return "", request
+}
+
+func unmarshalEvent(request json.RawMessage, event interface{}) {
+	err := json.Unmarshal(request, event)
+	if err != nil {
+		panic(fmt.Errorf("event unmarshal failed : %v", err))
+	}
+}
@@ -1,13 +1,23 @@
 package eventprocessor
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"gobase-lambda/aws"
+	"gobase-lambda/errornotification"
 	"gobase-lambda/log"
 	"gobase-lambda/utils"
 
 	"github.com/aws/aws-lambda-go/events"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 )
 
@@ -15,6 +25,18 @@ type Handler struct {
 	isLambda           bool
 	log                *log.Log
 	eventProcessorFunc NewEventProcessor
+	builtinMiddleware  []Middleware
+	middleware         []Middleware
+	eventMiddleware    map[EventType][]Middleware
+	cors               *CORSConfig
+	compression        *CompressionConfig
+	jwtVerifier        *JWTVerifier
+	errorRenderer      ErrorRenderer
+	timeoutMargin      time.Duration
+	openAPI            *OpenAPIInfo
+	openAPIOnce        sync.Once
+	openAPIBody        []byte
+	openAPIErr         error
 }
 
 func (h *Handler) setAWSSession() {
@@ -45,6 +67,30 @@ func GetHandler(isAWSEnv bool, eventProcessorCreator NewEventProcessor) *Handler
 	return handler
 }
 
+// GetOfflineHandler returns a Handler which doesn't need an AWS session, for build time tools like the
+// OpenAPI generator and route validation which only read the routes. The AWS clients created while
+// registering the routes get a session without credentials and can't be called.
+func GetOfflineHandler(eventProcessorCreator NewEventProcessor) *Handler {
+	if aws.GetDefaultAWSSession() == nil {
+		aws.SetDefaultAWSSession(session.Must(session.NewSession(&awssdk.Config{Credentials: credentials.AnonymousCredentials})))
+	}
+	return GetHandler(false, eventProcessorCreator)
+}
+
+// ValidateRoutes checks the routes returned by GetAPIHandler, it is meant to be called once at cold
+// start so that broken routes fail the deployment instead of returning 404 at request time.
+func (h *Handler) ValidateRoutes() error {
+	eventProcessor := h.eventProcessorFunc(context.Background(), h.log, nil, EventAPI)
+	return ValidateRoutes(eventProcessor.GetAPIHandler())
+}
+
+func (h *Handler) MustValidateRoutes() {
+	if err := h.ValidateRoutes(); err != nil {
+		h.log.Alert("Invalid routes", err.Error())
+		panic(err)
+	}
+}
+
 func (h *Handler) setCorrelationParams(correlationParams map[string]string) {
 	h.log.SetCorrelationParams(correlationParams)
 }
@@ -52,29 +98,112 @@ func (h *Handler) setCorrelationParams(correlationParams map[string]string) {
 var reduced = "<<<reduced>>>"
 var reducedList = []string{reduced}
 
-func (h *Handler) printReducedRequest(request *events.APIGatewayProxyRequest, isReduceBody bool) {
+// redactedHeaders are matched case insensitively as HTTP APIs, function URLs and load balancers send
+// lower case header names.
+var redactedHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+}
+
+// redactRequest returns a copy of the request with the credential headers redacted, the request is
+// left untouched as the handler can be reading its headers concurrently.
+func redactRequest(request *events.APIGatewayProxyRequest) events.APIGatewayProxyRequest {
+	redacted := *request
+	if request.Headers != nil {
+		redacted.Headers = make(map[string]string, len(request.Headers))
+		for key, value := range request.Headers {
+			if redactedHeaders[strings.ToLower(key)] {
+				value = reduced
+			}
+			redacted.Headers[key] = value
+		}
+	}
+	if request.MultiValueHeaders != nil {
+		redacted.MultiValueHeaders = make(map[string][]string, len(request.MultiValueHeaders))
+		for key, values := range request.MultiValueHeaders {
+			if redactedHeaders[strings.ToLower(key)] {
+				values = reducedList
+			}
+			redacted.MultiValueHeaders[key] = values
+		}
+	}
+	return redacted
+}
 
-	body := request.Body
+func printReducedRequest(logger *log.Log, request *events.APIGatewayProxyRequest, isReduceBody bool) {
+	reducedRequest := redactRequest(request)
+	reducedRequest.RequestContext = events.APIGatewayProxyRequestContext{}
 	if isReduceBody {
-		request.Body = reduced
+		reducedRequest.Body = reduced
 	}
-	context := request.RequestContext
-	request.RequestContext = events.APIGatewayProxyRequestContext{}
-	authorization, aok := request.Headers["Authorization"]
-	if aok {
-		request.Headers["Authorization"] = reduced
+	logger.Info("API Request", reducedRequest)
+}
+
+type RecordError struct {
+	RecordId string      `json:"recordId"`
+	Error    interface{} `json:"error"`
+}
+
+func newRecordError(recordId string, err error) *RecordError {
+	if custErr, ok := err.(*utils.Error); ok {
+		return &RecordError{RecordId: recordId, Error: custErr}
 	}
-	mAuthorization, mok := request.MultiValueHeaders["Authorization"]
-	if mok {
-		request.MultiValueHeaders["Authorization"] = reducedList
+	return &RecordError{RecordId: recordId, Error: err.Error()}
+}
+
+// recordPanicError converts a panic recovered while processing a single record of a batch into an error.
+func (h *Handler) recordPanicError(r interface{}, record interface{}, notify bool) error {
+	h.log.Error("Record", record)
+	h.log.Error("Panic Stack", string(debug.Stack()))
+	h.log.Error("Panic Recovery", r)
+	switch v := r.(type) {
+	case *utils.Error:
+		return v
+	case error:
+		if notify {
+			notifyError(h.log, r)
+		}
+		return v
+	default:
+		if notify {
+			notifyError(h.log, r)
+		}
+		return fmt.Errorf("%v", r)
 	}
-	h.log.Info("API Request", request)
-	request.RequestContext = context
-	request.Body = body
-	if aok {
-		request.Headers["Authorization"] = authorization
+}
+
+func notifyError(logger *log.Log, r interface{}) {
+	notification := errornotification.ErrorNotifier{
+		StatusCode:   "500",
+		StackTrace:   string(debug.Stack()),
+		ErrorMessage: fmt.Sprintf("%s", r),
+		Log:          *logger,
+	}
+	notification.PublishToSqs()
+	notification.PublishToSns()
+}
+
+// newRecordsError collects the per record errors of a batch. The error of a single record event is
+// returned as is, otherwise the status code is kept only when all the records failed with the same one.
+func newRecordsError(total int, recordErrors []*RecordError, errs []error) error {
+	if len(errs) == 0 {
+		return nil
 	}
-	if mok {
-		request.MultiValueHeaders["Authorization"] = mAuthorization
+	if total == 1 {
+		return errs[0]
+	}
+	statusCode := 0
+	for _, err := range errs {
+		code := http.StatusInternalServerError
+		if custErr, ok := err.(*utils.Error); ok {
+			code = custErr.StatusCode
+		}
+		if statusCode != 0 && statusCode != code {
+			statusCode = http.StatusInternalServerError
+			break
+		}
+		statusCode = code
 	}
+	return utils.NewError(statusCode, fmt.Sprintf("%v of %v records failed", len(errs), total), "RECORD_PROCESSING_FAILED", recordErrors)
 }
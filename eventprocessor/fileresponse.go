@@ -0,0 +1,79 @@
+package eventprocessor
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/gabriel-vasile/mimetype"
+	"gobase-lambda/utils"
+)
+
+// maxResponseOverhead is kept free in the response payload for the status code and the headers.
+const maxResponseOverhead = 64 * 1024
+
+// FileResponse is a binary or file download response, return it from an API handler in place of the
+// response object. Body or Reader holds the content, ContentType is detected from the content when it
+// is empty and FileName sets the Content-Disposition header, as an attachment unless Inline is set.
+// The content is sent base64 encoded, REST APIs need the binary media types configured to pass it as is.
+//
+//	return http.StatusOK, &eventprocessor.FileResponse{Body: report, ContentType: "application/pdf", FileName: "report.pdf"}, nil
+type FileResponse struct {
+	Body        []byte
+	Reader      io.Reader
+	ContentType string
+	FileName    string
+	Inline      bool
+}
+
+// maxFileResponseSize is the largest content which fits in the Lambda response payload once base64 encoded.
+func maxFileResponseSize() int {
+	return base64.StdEncoding.DecodedLen(maxLambdaPayloadSize - maxResponseOverhead)
+}
+
+func (f *FileResponse) content() []byte {
+	if f.Reader == nil {
+		return f.Body
+	}
+	if closer, ok := f.Reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	data, err := io.ReadAll(io.LimitReader(f.Reader, int64(maxFileResponseSize())+1))
+	if err != nil {
+		panic(utils.NewError(http.StatusInternalServerError, fmt.Sprintf("file response reading failed : %v", err), "RESPONSE_ENCODING_FAILED", nil))
+	}
+	return data
+}
+
+func (f *FileResponse) proxyResponse(statusCode int) (res events.APIGatewayProxyResponse) {
+	data := f.content()
+	if len(data) > maxFileResponseSize() {
+		panic(utils.NewError(http.StatusInternalServerError, fmt.Sprintf("file response exceeds %v bytes, the Lambda response payload limit, return a presigned URL for large files", maxFileResponseSize()), "RESPONSE_TOO_LARGE", map[string]string{
+			"fileName": f.FileName,
+		}))
+	}
+	contentType := f.ContentType
+	if contentType == "" {
+		contentType = mimetype.Detect(data).String()
+	}
+	res.StatusCode = statusCode
+	res.Headers = map[string]string{
+		"Content-Type":   contentType,
+		"Content-Length": strconv.Itoa(len(data)),
+	}
+	switch {
+	case f.FileName != "" && f.Inline:
+		res.Headers["Content-Disposition"] = mime.FormatMediaType("inline", map[string]string{"filename": f.FileName})
+	case f.FileName != "":
+		res.Headers["Content-Disposition"] = mime.FormatMediaType("attachment", map[string]string{"filename": f.FileName})
+	case f.Inline:
+		res.Headers["Content-Disposition"] = "inline"
+	}
+	res.Body = base64.StdEncoding.EncodeToString(data)
+	res.IsBase64Encoded = true
+	return
+}
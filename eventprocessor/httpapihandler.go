@@ -0,0 +1,186 @@
+package eventprocessor
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const defaultRouteKey = "$default"
+
+// HandleHTTPAPIRequest handles API Gateway HTTP API (payload format 2.0) events with the same route
+// map as HandleAPIRequest. The route is taken from the routeKey, routes which are not in the route map,
+// like $default or ANY /{proxy+}, are matched on the raw path. Request cookies are available in the cookie header and Set-Cookie response headers are
+// returned as cookies.
+func (h *Handler) HandleHTTPAPIRequest(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	proxyRequest := newProxyRequestFromV2(&request)
+	res, err := h.processAPIRequest(ctx, proxyRequest, &request, EventHTTPAPI)
+	headers, multiValueHeaders, cookies := extractCookies(&res)
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode:        res.StatusCode,
+		Headers:           headers,
+		MultiValueHeaders: multiValueHeaders,
+		Body:              res.Body,
+		IsBase64Encoded:   res.IsBase64Encoded,
+		Cookies:           cookies,
+	}, err
+}
+
+// HandleFunctionURLRequest handles Lambda function URL events, routes are matched on the raw path.
+func (h *Handler) HandleFunctionURLRequest(ctx context.Context, request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	v2Request := &events.APIGatewayV2HTTPRequest{
+		Version:               request.Version,
+		RouteKey:              defaultRouteKey,
+		RawPath:               request.RawPath,
+		RawQueryString:        request.RawQueryString,
+		Cookies:               request.Cookies,
+		Headers:               request.Headers,
+		QueryStringParameters: request.QueryStringParameters,
+		Body:                  request.Body,
+		IsBase64Encoded:       request.IsBase64Encoded,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey:     defaultRouteKey,
+			AccountID:    request.RequestContext.AccountID,
+			RequestID:    request.RequestContext.RequestID,
+			APIID:        request.RequestContext.APIID,
+			DomainName:   request.RequestContext.DomainName,
+			DomainPrefix: request.RequestContext.DomainPrefix,
+			Time:         request.RequestContext.Time,
+			TimeEpoch:    request.RequestContext.TimeEpoch,
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    request.RequestContext.HTTP.Method,
+				Path:      request.RequestContext.HTTP.Path,
+				Protocol:  request.RequestContext.HTTP.Protocol,
+				SourceIP:  request.RequestContext.HTTP.SourceIP,
+				UserAgent: request.RequestContext.HTTP.UserAgent,
+			},
+		},
+	}
+	proxyRequest := newProxyRequestFromV2(v2Request)
+	res, err := h.processAPIRequest(ctx, proxyRequest, &request, EventFunctionURL)
+	headers, multiValueHeaders, cookies := extractCookies(&res)
+	for key, values := range multiValueHeaders {
+		headers[key] = strings.Join(values, ",")
+	}
+	return events.LambdaFunctionURLResponse{
+		StatusCode:      res.StatusCode,
+		Headers:         headers,
+		Body:            res.Body,
+		IsBase64Encoded: res.IsBase64Encoded,
+		Cookies:         cookies,
+	}, err
+}
+
+func newProxyRequestFromV2(request *events.APIGatewayV2HTTPRequest) *events.APIGatewayProxyRequest {
+	method := request.RequestContext.HTTP.Method
+	resource := ""
+	if routeKey := strings.SplitN(request.RouteKey, " ", 2); len(routeKey) == 2 {
+		resource = routeKey[1]
+	}
+	headers := make(map[string]string, len(request.Headers)+1)
+	multiValueHeaders := make(map[string][]string, len(request.Headers)+1)
+	for key, value := range request.Headers {
+		headers[key] = value
+		multiValueHeaders[key] = []string{value}
+	}
+	if len(request.Cookies) > 0 {
+		headers["cookie"] = strings.Join(request.Cookies, "; ")
+		multiValueHeaders["cookie"] = request.Cookies
+	}
+	queryParams := make(map[string]string, len(request.QueryStringParameters))
+	for key, value := range request.QueryStringParameters {
+		queryParams[key] = value
+	}
+	multiValueQueryParams, err := url.ParseQuery(request.RawQueryString)
+	if err != nil {
+		multiValueQueryParams = make(url.Values, len(queryParams))
+		for key, value := range queryParams {
+			multiValueQueryParams[key] = strings.Split(value, ",")
+		}
+	}
+	for key, values := range multiValueQueryParams {
+		if _, ok := queryParams[key]; !ok && len(values) > 0 {
+			queryParams[key] = values[len(values)-1]
+		}
+	}
+	authorizer := make(map[string]interface{})
+	if request.RequestContext.Authorizer != nil {
+		if jwt := request.RequestContext.Authorizer.JWT; jwt != nil {
+			authorizer["claims"] = jwt.Claims
+			authorizer["scopes"] = jwt.Scopes
+		}
+		for key, value := range request.RequestContext.Authorizer.Lambda {
+			authorizer[key] = value
+		}
+	}
+	return &events.APIGatewayProxyRequest{
+		Resource:                        resource,
+		Path:                            request.RawPath,
+		HTTPMethod:                      method,
+		Headers:                         headers,
+		MultiValueHeaders:               multiValueHeaders,
+		QueryStringParameters:           queryParams,
+		MultiValueQueryStringParameters: multiValueQueryParams,
+		PathParameters:                  request.PathParameters,
+		StageVariables:                  request.StageVariables,
+		Body:                            request.Body,
+		IsBase64Encoded:                 request.IsBase64Encoded,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			AccountID:        request.RequestContext.AccountID,
+			Stage:            request.RequestContext.Stage,
+			DomainName:       request.RequestContext.DomainName,
+			DomainPrefix:     request.RequestContext.DomainPrefix,
+			RequestID:        request.RequestContext.RequestID,
+			Protocol:         request.RequestContext.HTTP.Protocol,
+			ResourcePath:     resource,
+			Path:             request.RequestContext.HTTP.Path,
+			Authorizer:       authorizer,
+			HTTPMethod:       method,
+			RequestTime:      request.RequestContext.Time,
+			RequestTimeEpoch: request.RequestContext.TimeEpoch,
+			APIID:            request.RequestContext.APIID,
+			Identity: events.APIGatewayRequestIdentity{
+				SourceIP:  request.RequestContext.HTTP.SourceIP,
+				UserAgent: request.RequestContext.HTTP.UserAgent,
+			},
+		},
+	}
+}
+
+// extractCookies moves the Set-Cookie headers of the response to the payload v2 cookies list.
+func extractCookies(res *events.APIGatewayProxyResponse) (headers map[string]string, multiValueHeaders map[string][]string, cookies []string) {
+	headers = make(map[string]string, len(res.Headers))
+	multiValueHeaders = make(map[string][]string, len(res.MultiValueHeaders))
+	for key, value := range res.Headers {
+		if strings.EqualFold(key, "Set-Cookie") {
+			cookies = append(cookies, value)
+			continue
+		}
+		headers[key] = value
+	}
+	for key, values := range res.MultiValueHeaders {
+		if strings.EqualFold(key, "Set-Cookie") {
+			cookies = append(cookies, values...)
+			continue
+		}
+		multiValueHeaders[key] = values
+	}
+	return
+}
+
+// GetCookies returns the request cookies from the cookie header.
+func GetCookies(headers map[string]string) map[string]string {
+	cookies := make(map[string]string)
+	for key, value := range headers {
+		if !strings.EqualFold(key, "Cookie") {
+			continue
+		}
+		for _, cookie := range (&http.Request{Header: http.Header{"Cookie": {value}}}).Cookies() {
+			cookies[cookie.Name] = cookie.Value
+		}
+	}
+	return cookies
+}
@@ -0,0 +1,154 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"gobase-lambda/aws"
+)
+
+// DynamoDbStore keeps the records in a table with the string partition key "key", the TTL of the table
+// has to be enabled on the "expiresAt" attribute.
+type DynamoDbStore struct {
+	client *aws.DynamoDb
+	table  string
+	ctx    context.Context
+}
+
+func NewDynamoDbStore(ctx context.Context, client *aws.DynamoDb, table string) *DynamoDbStore {
+	return &DynamoDbStore{client: client, table: table, ctx: ctx}
+}
+
+func (s *DynamoDbStore) Acquire(record *Record) (*Record, bool, error) {
+	return acquireWithRetry(record, s.acquire)
+}
+
+func (s *DynamoDbStore) acquire(record *Record) (*Record, bool, error) {
+	// items are deleted up to a few days after they expire, so expired and stale records are taken over
+	_, err := s.client.Client.PutItemWithContext(s.ctx, &dynamodb.PutItemInput{
+		TableName:           awssdk.String(s.table),
+		Item:                toDynamoDbItem(record),
+		ConditionExpression: awssdk.String("attribute_not_exists(#key) OR #expiresAt < :now OR (#status = :inProgress AND #lockedUntil < :now)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#key":         awssdk.String("key"),
+			"#expiresAt":   awssdk.String("expiresAt"),
+			"#status":      awssdk.String("status"),
+			"#lockedUntil": awssdk.String("lockedUntil"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now":        numberValue(time.Now().Unix()),
+			":inProgress": {S: awssdk.String(StatusInProgress)},
+		},
+	})
+	if err == nil {
+		return record, true, nil
+	}
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) || awsErr.Code() != dynamodb.ErrCodeConditionalCheckFailedException {
+		return nil, false, err
+	}
+	output, err := s.client.Client.GetItemWithContext(s.ctx, &dynamodb.GetItemInput{
+		TableName:      awssdk.String(s.table),
+		Key:            map[string]*dynamodb.AttributeValue{"key": {S: awssdk.String(record.Key)}},
+		ConsistentRead: awssdk.Bool(true),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if output.Item == nil {
+		return nil, false, errRecordRemoved
+	}
+	return fromDynamoDbItem(output.Item), false, nil
+}
+
+func (s *DynamoDbStore) Complete(record *Record) error {
+	_, err := s.client.Client.UpdateItemWithContext(s.ctx, &dynamodb.UpdateItemInput{
+		TableName:           awssdk.String(s.table),
+		Key:                 map[string]*dynamodb.AttributeValue{"key": {S: awssdk.String(record.Key)}},
+		UpdateExpression:    awssdk.String("SET #status = :status, #response = :response, #expiresAt = :expiresAt"),
+		ConditionExpression: awssdk.String("#fingerprint = :fingerprint"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status":      awssdk.String("status"),
+			"#response":    awssdk.String("response"),
+			"#expiresAt":   awssdk.String("expiresAt"),
+			"#fingerprint": awssdk.String("fingerprint"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":status":      {S: awssdk.String(record.Status)},
+			":response":    {S: awssdk.String(record.Response)},
+			":expiresAt":   numberValue(record.ExpiresAt.Unix()),
+			":fingerprint": {S: awssdk.String(record.Fingerprint)},
+		},
+	})
+	return err
+}
+
+func (s *DynamoDbStore) Release(record *Record) error {
+	_, err := s.client.Client.DeleteItemWithContext(s.ctx, &dynamodb.DeleteItemInput{
+		TableName:           awssdk.String(s.table),
+		Key:                 map[string]*dynamodb.AttributeValue{"key": {S: awssdk.String(record.Key)}},
+		ConditionExpression: awssdk.String("#fingerprint = :fingerprint AND #status = :inProgress"),
+		ExpressionAttributeNames: map[string]*string{
+			"#fingerprint": awssdk.String("fingerprint"),
+			"#status":      awssdk.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":fingerprint": {S: awssdk.String(record.Fingerprint)},
+			":inProgress":  {S: awssdk.String(StatusInProgress)},
+		},
+	})
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return nil
+	}
+	return err
+}
+
+func toDynamoDbItem(record *Record) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{
+		"key":         {S: awssdk.String(record.Key)},
+		"fingerprint": {S: awssdk.String(record.Fingerprint)},
+		"status":      {S: awssdk.String(record.Status)},
+		"lockedUntil": numberValue(record.LockedUntil.Unix()),
+		"expiresAt":   numberValue(record.ExpiresAt.Unix()),
+	}
+	if record.Response != "" {
+		item["response"] = &dynamodb.AttributeValue{S: awssdk.String(record.Response)}
+	}
+	return item
+}
+
+func fromDynamoDbItem(item map[string]*dynamodb.AttributeValue) *Record {
+	return &Record{
+		Key:         stringValue(item["key"]),
+		Fingerprint: stringValue(item["fingerprint"]),
+		Status:      stringValue(item["status"]),
+		Response:    stringValue(item["response"]),
+		LockedUntil: timeValue(item["lockedUntil"]),
+		ExpiresAt:   timeValue(item["expiresAt"]),
+	}
+}
+
+func numberValue(value int64) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: awssdk.String(strconv.FormatInt(value, 10))}
+}
+
+func stringValue(value *dynamodb.AttributeValue) string {
+	if value == nil || value.S == nil {
+		return ""
+	}
+	return *value.S
+}
+
+func timeValue(value *dynamodb.AttributeValue) time.Time {
+	if value == nil || value.N == nil {
+		return time.Time{}
+	}
+	seconds, _ := strconv.ParseInt(*value.N, 10, 64)
+	return time.Unix(seconds, 0)
+}
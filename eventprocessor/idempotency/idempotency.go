@@ -0,0 +1,231 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/utils"
+)
+
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// HeaderReplayed is set on the responses replayed from the store.
+const HeaderReplayed = "Idempotent-Replayed"
+
+const (
+	StatusInProgress = "IN_PROGRESS"
+	StatusCompleted  = "COMPLETED"
+)
+
+const (
+	defaultTTL         = 24 * time.Hour
+	defaultLockTimeout = time.Minute
+	maxKeyLength       = 255
+	acquireAttempts    = 3
+)
+
+var (
+	errRecordTakenOver = errors.New("idempotency record was taken over")
+	errRecordRemoved   = errors.New("idempotency record was removed concurrently")
+)
+
+// Record is the stored state of an idempotency key. Key is derived from the route, the principal and
+// the Idempotency-Key header, Fingerprint from the request, and Response is the JSON encoded response.
+type Record struct {
+	Key         string
+	Fingerprint string
+	Status      string
+	Response    string
+	LockedUntil time.Time
+	ExpiresAt   time.Time
+}
+
+// Store keeps the idempotency records, records are expected to be removed after ExpiresAt by the TTL
+// of the collection or table.
+type Store interface {
+	// Acquire saves the in progress record unless the key is taken by a record which is neither expired
+	// nor a stale in progress one, the current record is returned with false in that case.
+	Acquire(record *Record) (*Record, bool, error)
+	// Complete saves the response of the in progress record.
+	Complete(record *Record) error
+	// Release deletes the in progress record so that the request can be retried.
+	Release(record *Record) error
+}
+
+// Config of the idempotency middleware. TTL is how long responses are replayed, 24 hours by default, and
+// LockTimeout is how long an in progress request blocks its duplicates, 1 minute by default, it has to
+// be longer than the Lambda timeout. Required rejects the requests without an Idempotency-Key.
+type Config struct {
+	Store       Store
+	TTL         time.Duration
+	LockTimeout time.Duration
+	Required    bool
+}
+
+// Middleware makes a route idempotent for the requests carrying an Idempotency-Key header. The first
+// request runs the handler and its response is stored, repeats get the stored response back, a repeat
+// while the first one is in progress gets 409 and reusing the key for a different request gets 422.
+// Errors and responses with status 5xx are not stored, so the request can be retried.
+//
+//	store := idempotency.NewDynamoDbStore(ctx, aws.GetDefaultDynamoDbClient(ctx), aws.GetTableName("IDEMPOTENCY"))
+//	r.POST("/payments", eventprocessor.Route(m.CreatePayment), idempotency.Middleware(idempotency.Config{Store: store}))
+func Middleware(config Config) eventprocessor.Middleware {
+	if config.Store == nil {
+		panic("idempotency store is not set")
+	}
+	if config.TTL <= 0 {
+		config.TTL = defaultTTL
+	}
+	if config.LockTimeout <= 0 {
+		config.LockTimeout = defaultLockTimeout
+	}
+	return eventprocessor.MiddlewareFunc(func(invocation *eventprocessor.Invocation, next eventprocessor.Next) (interface{}, error) {
+		return config.handle(invocation, next)
+	})
+}
+
+func (c *Config) handle(invocation *eventprocessor.Invocation, next eventprocessor.Next) (result interface{}, err error) {
+	request := invocation.APIRequest
+	idempotencyKey := strings.TrimSpace(getHeader(request.Headers, HeaderIdempotencyKey))
+	if idempotencyKey == "" {
+		if c.Required {
+			return nil, utils.NewError(http.StatusBadRequest, fmt.Sprintf("%v header is required", HeaderIdempotencyKey), "IDEMPOTENCY_KEY_REQUIRED", nil)
+		}
+		return next(invocation)
+	}
+	if len(idempotencyKey) > maxKeyLength {
+		return nil, utils.NewError(http.StatusBadRequest, fmt.Sprintf("%v header exceeds %v characters", HeaderIdempotencyKey, maxKeyLength), "INVALID_IDEMPOTENCY_KEY", nil)
+	}
+	now := time.Now()
+	record := &Record{
+		Key:         recordKey(invocation, idempotencyKey),
+		Fingerprint: fingerprint(request),
+		Status:      StatusInProgress,
+		LockedUntil: now.Add(c.LockTimeout),
+		ExpiresAt:   now.Add(c.TTL),
+	}
+	current, acquired, err := c.Store.Acquire(record)
+	if err != nil {
+		invocation.Log.Error("Idempotency store error", err.Error())
+		return nil, utils.NewError(http.StatusInternalServerError, "idempotency key could not be saved", "IDEMPOTENCY_STORE_FAILED", nil)
+	}
+	if !acquired {
+		return replay(current, record)
+	}
+	completed := false
+	defer func() {
+		if !completed {
+			if releaseErr := c.Store.Release(record); releaseErr != nil {
+				invocation.Log.Error("Idempotency key release error", releaseErr.Error())
+			}
+		}
+	}()
+	result, err = next(invocation)
+	res, ok := result.(events.APIGatewayProxyResponse)
+	if err != nil || !ok || res.StatusCode >= http.StatusInternalServerError {
+		return result, err
+	}
+	response, err := json.Marshal(res)
+	if err != nil {
+		return result, nil
+	}
+	record.Status, record.Response = StatusCompleted, string(response)
+	if completeErr := c.Store.Complete(record); completeErr != nil {
+		invocation.Log.Error("Idempotency store error", completeErr.Error())
+		return result, nil
+	}
+	completed = true
+	return result, nil
+}
+
+// acquireWithRetry retries when the record blocking the key is removed before it could be read, the key
+// is reported in progress when that keeps happening so that the duplicate gets a 409 and retries.
+func acquireWithRetry(record *Record, acquire func(*Record) (*Record, bool, error)) (*Record, bool, error) {
+	for attempt := 0; attempt < acquireAttempts; attempt++ {
+		current, acquired, err := acquire(record)
+		if err != errRecordRemoved {
+			return current, acquired, err
+		}
+	}
+	inProgress := *record
+	return &inProgress, false, nil
+}
+
+// replay answers a duplicate request from the record saved by the first one.
+func replay(current, record *Record) (interface{}, error) {
+	if current.Fingerprint != record.Fingerprint {
+		return nil, utils.NewError(http.StatusUnprocessableEntity, fmt.Sprintf("%v was used for a different request", HeaderIdempotencyKey), "IDEMPOTENCY_KEY_REUSED", nil)
+	}
+	if current.Status != StatusCompleted {
+		return nil, utils.NewError(http.StatusConflict, "a request with the same idempotency key is in progress", "REQUEST_IN_PROGRESS", nil)
+	}
+	res := events.APIGatewayProxyResponse{}
+	if err := json.Unmarshal([]byte(current.Response), &res); err != nil {
+		return nil, err
+	}
+	if res.Headers == nil {
+		res.Headers = make(map[string]string)
+	}
+	res.Headers[HeaderReplayed] = "true"
+	return res, nil
+}
+
+// recordKey scopes the key to the route and the principal, so keys of different callers never clash.
+func recordKey(invocation *eventprocessor.Invocation, idempotencyKey string) string {
+	subject := ""
+	if invocation.Principal != nil {
+		subject = invocation.Principal.Subject
+	}
+	route := invocation.APIRequest.HTTPMethod + " " + invocation.APIRequest.Resource
+	if invocation.API != nil {
+		route = invocation.API.Method + " " + invocation.API.Resource
+	}
+	return hash(route, subject, idempotencyKey)
+}
+
+// fingerprint identifies the request by its method, path, query string and body.
+func fingerprint(request *events.APIGatewayProxyRequest) string {
+	queryParams := make([]string, 0)
+	for key, values := range request.MultiValueQueryStringParameters {
+		for _, value := range values {
+			queryParams = append(queryParams, key+"="+value)
+		}
+	}
+	if len(queryParams) == 0 {
+		for key, value := range request.QueryStringParameters {
+			queryParams = append(queryParams, key+"="+value)
+		}
+	}
+	sort.Strings(queryParams)
+	return hash(request.HTTPMethod, request.Path, strings.Join(queryParams, "&"), request.Body)
+}
+
+func hash(values ...string) string {
+	hasher := sha256.New()
+	for _, value := range values {
+		hasher.Write([]byte(value))
+		hasher.Write([]byte{0})
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+func getHeader(headers map[string]string, name string) string {
+	if value, ok := headers[name]; ok {
+		return value
+	}
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
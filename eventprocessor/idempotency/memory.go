@@ -0,0 +1,48 @@
+package idempotency
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the records in the memory of the Lambda instance, for tests and local runs as the
+// duplicates reaching other instances are not detected.
+type MemoryStore struct {
+	mutex   sync.Mutex
+	records map[string]Record
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]Record)}
+}
+
+func (s *MemoryStore) Acquire(record *Record) (*Record, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	if current, ok := s.records[record.Key]; ok && current.ExpiresAt.After(now) &&
+		(current.Status != StatusInProgress || current.LockedUntil.After(now)) {
+		return &current, false, nil
+	}
+	s.records[record.Key] = *record
+	return record, true, nil
+}
+
+func (s *MemoryStore) Complete(record *Record) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if current, ok := s.records[record.Key]; !ok || current.Fingerprint != record.Fingerprint {
+		return errRecordTakenOver
+	}
+	s.records[record.Key] = *record
+	return nil
+}
+
+func (s *MemoryStore) Release(record *Record) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if current, ok := s.records[record.Key]; ok && current.Fingerprint == record.Fingerprint && current.Status == StatusInProgress {
+		delete(s.records, record.Key)
+	}
+	return nil
+}
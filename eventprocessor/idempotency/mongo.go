@@ -0,0 +1,122 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	dbmongo "gobase-lambda/db/mongo"
+)
+
+type mongoRecord struct {
+	Key         string    `bson:"_id"`
+	Fingerprint string    `bson:"fingerprint"`
+	Status      string    `bson:"status"`
+	Response    string    `bson:"response,omitempty"`
+	LockedUntil time.Time `bson:"lockedUntil"`
+	ExpiresAt   time.Time `bson:"expiresAt"`
+}
+
+// MongoStore keeps the records in a collection, expired records are removed by the TTL index created
+// with CreateTTLIndex.
+type MongoStore struct {
+	collection *dbmongo.Collection
+	ctx        context.Context
+}
+
+func NewMongoStore(ctx context.Context, collection *dbmongo.Collection) *MongoStore {
+	return &MongoStore{collection: collection, ctx: ctx}
+}
+
+// CreateTTLIndex creates the TTL index on expiresAt, it is a no-op when the index exists.
+func (s *MongoStore) CreateTTLIndex() error {
+	_, err := s.collection.Collection.Indexes().CreateOne(s.ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+func (s *MongoStore) Acquire(record *Record) (*Record, bool, error) {
+	return acquireWithRetry(record, s.acquire)
+}
+
+func (s *MongoStore) acquire(record *Record) (*Record, bool, error) {
+	document := toMongoRecord(record)
+	_, err := s.collection.Collection.InsertOne(s.ctx, document)
+	if err == nil {
+		return record, true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, false, err
+	}
+	// the TTL monitor runs every minute, expired and stale records are taken over
+	now := time.Now()
+	filter := bson.M{
+		"_id": record.Key,
+		"$or": bson.A{
+			bson.M{"expiresAt": bson.M{"$lt": now}},
+			bson.M{"status": StatusInProgress, "lockedUntil": bson.M{"$lt": now}},
+		},
+	}
+	res, err := s.collection.Collection.ReplaceOne(s.ctx, filter, document)
+	if err != nil {
+		return nil, false, err
+	}
+	if res.MatchedCount == 1 {
+		return record, true, nil
+	}
+	current := &mongoRecord{}
+	err = s.collection.Collection.FindOne(s.ctx, bson.M{"_id": record.Key}).Decode(current)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, errRecordRemoved
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return current.record(), false, nil
+}
+
+// Complete fails when the record expired or was taken over, the response is not stored then.
+func (s *MongoStore) Complete(record *Record) error {
+	res, err := s.collection.Collection.UpdateOne(s.ctx, bson.M{"_id": record.Key, "fingerprint": record.Fingerprint}, bson.M{
+		"$set": bson.M{"status": record.Status, "response": record.Response, "expiresAt": record.ExpiresAt},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errRecordTakenOver
+	}
+	return nil
+}
+
+func (s *MongoStore) Release(record *Record) error {
+	_, err := s.collection.Collection.DeleteOne(s.ctx, bson.M{"_id": record.Key, "fingerprint": record.Fingerprint, "status": StatusInProgress})
+	return err
+}
+
+func toMongoRecord(record *Record) *mongoRecord {
+	return &mongoRecord{
+		Key:         record.Key,
+		Fingerprint: record.Fingerprint,
+		Status:      record.Status,
+		Response:    record.Response,
+		LockedUntil: record.LockedUntil,
+		ExpiresAt:   record.ExpiresAt,
+	}
+}
+
+func (r *mongoRecord) record() *Record {
+	return &Record{
+		Key:         r.Key,
+		Fingerprint: r.Fingerprint,
+		Status:      r.Status,
+		Response:    r.Response,
+		LockedUntil: r.LockedUntil,
+		ExpiresAt:   r.ExpiresAt,
+	}
+}
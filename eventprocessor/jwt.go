@@ -0,0 +1,312 @@
+package eventprocessor
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gobase-lambda/utils/http"
+)
+
+// jwksRefreshInterval limits how often a JWKS loaded from a URL is fetched again for an unknown key id.
+const jwksRefreshInterval = 5 * time.Minute
+
+// JWKS is a set of public keys used to verify JWT signatures, keyed by the key id. Keys loaded from a
+// URL are fetched again when a token is signed with an unknown key, to pick up key rotation, at most
+// once per RefreshInterval, 5 minutes when it is zero.
+type JWKS struct {
+	RefreshInterval time.Duration
+	url             string
+	mutex           sync.RWMutex
+	keys            map[string]crypto.PublicKey
+	refreshedAt     time.Time
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func LoadJWKSFromFile(path string) (*JWKS, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parseJWKS(blob)
+	if err != nil {
+		return nil, err
+	}
+	return &JWKS{keys: keys}, nil
+}
+
+// LoadJWKSFromURL fetches the JWKS, for Cognito user pools the URL is
+// https://cognito-idp.<region>.amazonaws.com/<userPoolId>/.well-known/jwks.json
+func LoadJWKSFromURL(url string) (*JWKS, error) {
+	jwks := &JWKS{url: url}
+	if err := jwks.refresh(); err != nil {
+		return nil, err
+	}
+	return jwks, nil
+}
+
+func (j *JWKS) refresh() error {
+	response, body, err := http.NewHTTPClient(context.Background()).Get(j.url, nil, nil, 10)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != 200 {
+		return fmt.Errorf("JWKS request failed with status %v", response.StatusCode)
+	}
+	keys, err := parseJWKS(body)
+	if err != nil {
+		return err
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.keys, j.refreshedAt = keys, time.Now()
+	return nil
+}
+
+func (j *JWKS) getKey(kid string) (crypto.PublicKey, error) {
+	j.mutex.RLock()
+	key, ok := j.keys[kid]
+	if !ok && kid == "" && len(j.keys) == 1 {
+		for _, key = range j.keys {
+			ok = true
+		}
+	}
+	refreshInterval := j.RefreshInterval
+	if refreshInterval <= 0 {
+		refreshInterval = jwksRefreshInterval
+	}
+	canRefresh := j.url != "" && time.Since(j.refreshedAt) > refreshInterval
+	j.mutex.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if canRefresh {
+		if err := j.refresh(); err != nil {
+			return nil, err
+		}
+		j.mutex.RLock()
+		key, ok = j.keys[kid]
+		j.mutex.RUnlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key id %v", kid)
+}
+
+func parseJWKS(blob []byte) (map[string]crypto.PublicKey, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(blob, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %v : %v", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %v", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %v", k.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	blob, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(blob), nil
+}
+
+// JWTVerifier verifies Bearer tokens locally, for APIs without an authorizer or behind function URLs and
+// load balancers. Issuer and Audience are checked when set, Cognito access tokens carry the app client
+// id in client_id instead of aud and it is accepted as the audience.
+//
+//	jwks, err := eventprocessor.LoadJWKSFromURL(jwksURL)
+//	handler.SetJWTVerifier(&eventprocessor.JWTVerifier{JWKS: jwks, Issuer: issuer, Audience: []string{clientId}})
+type JWTVerifier struct {
+	JWKS     *JWKS
+	Issuer   string
+	Audience []string
+	TokenUse string
+	Leeway   time.Duration
+}
+
+func (h *Handler) SetJWTVerifier(verifier *JWTVerifier) {
+	h.jwtVerifier = verifier
+}
+
+// Verify checks the signature and the registered claims of the token and returns its claims.
+func (v *JWTVerifier) Verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeTokenPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature")
+	}
+	key, err := v.JWKS.getKey(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+	claims := make(map[string]interface{})
+	if err = decodeTokenPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return claims, v.verifyClaims(claims)
+}
+
+func decodeTokenPart(part string, value interface{}) error {
+	blob, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return fmt.Errorf("malformed token")
+	}
+	if err = json.Unmarshal(blob, value); err != nil {
+		return fmt.Errorf("malformed token")
+	}
+	return nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %v", alg)
+	}
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %v", alg)
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+	switch publicKey := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(publicKey, hash, digest, signature)
+		case "PS":
+			return rsa.VerifyPSS(publicKey, hash, digest, signature, nil)
+		}
+	case *ecdsa.PublicKey:
+		if alg[:2] == "ES" {
+			size := (publicKey.Curve.Params().BitSize + 7) / 8
+			if len(signature) != 2*size {
+				return fmt.Errorf("invalid signature")
+			}
+			r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+			if !ecdsa.Verify(publicKey, digest, r, s) {
+				return fmt.Errorf("invalid signature")
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported algorithm %v", alg)
+}
+
+func (v *JWTVerifier) verifyClaims(claims map[string]interface{}) error {
+	now := time.Now()
+	if exp, ok := claims["exp"].(float64); !ok || now.After(time.Unix(int64(exp), 0).Add(v.Leeway)) {
+		return fmt.Errorf("token is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("token is not valid yet")
+	}
+	if v.Issuer != "" && stringClaim(claims, "iss") != v.Issuer {
+		return fmt.Errorf("invalid issuer")
+	}
+	if v.TokenUse != "" && stringClaim(claims, "token_use") != v.TokenUse {
+		return fmt.Errorf("invalid token use")
+	}
+	if len(v.Audience) > 0 {
+		audience := listClaim(claims["aud"])
+		if clientId := stringClaim(claims, "client_id"); clientId != "" {
+			audience = append(audience, clientId)
+		}
+		for _, aud := range audience {
+			if containsString(v.Audience, aud) {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid audience")
+	}
+	return nil
+}
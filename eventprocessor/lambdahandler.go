@@ -0,0 +1,105 @@
+package eventprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/lambda/messages"
+	"gobase-lambda/utils"
+)
+
+type LambdaEvent struct {
+	IsLambdaInvocation bool        `json:"isLambdaInvocation"`
+	ActionName         string      `json:"actionName"`
+	Payload            interface{} `json:"payload"`
+}
+
+type LambdaHandler func(payload interface{}) (statusCode int, response interface{}, err error)
+
+// LambdaContextHandler is a LambdaHandler receiving the ctx of the invocation.
+type LambdaContextHandler func(ctx context.Context, payload interface{}) (statusCode int, response interface{}, err error)
+
+// LambdaInvocation maps an action to its handler, LambdaContextHandlerFunc is used instead of
+// LambdaHandlerFunc when it is set.
+type LambdaInvocation struct {
+	LambdaHandlerFunc        LambdaHandler
+	LambdaContextHandlerFunc LambdaContextHandler
+}
+
+// HandleLambdaInvocation handles direct invocations (Lambda Invoke API, Step Functions task states).
+// The request can be a LambdaEvent or its raw JSON string and must have isLambdaInvocation set. The
+// handler response is returned as is, so it can be used directly as the state output, and errors are
+// returned with the error code as the error type so that state machines can Retry / Catch on it.
+func (h *Handler) HandleLambdaInvocation(ctx context.Context, request interface{}) (interface{}, error) {
+	invocation := h.newInvocation(ctx, EventLambda, request)
+	result, err := h.invoke(invocation, func(invocation *Invocation) (interface{}, error) {
+		event := parseLambdaEvent(request)
+		invocation.Event = event
+		if !event.IsLambdaInvocation {
+			panic(utils.NewHTTPBadRequestError("isLambdaInvocation is not set", request))
+		}
+		eventProcessor := h.eventProcessorFunc(invocation.Ctx, invocation.Log, event, EventLambda)
+		lambdaHandlerMap := eventProcessor.GetLambdaHandler()
+		actionHandler, ok := lambdaHandlerMap[event.ActionName]
+		if !ok {
+			errorMessage := fmt.Sprintf("action %v is not mapped", event.ActionName)
+			invocation.Log.Alert(errorMessage, lambdaHandlerMap)
+			panic(utils.NewHTTPNotFoundError(errorMessage, nil))
+		}
+		statusCode, response, err := actionHandler.call(invocation.Ctx, event.Payload)
+		if err == nil && statusCode > 299 {
+			err = utils.NewError(statusCode, fmt.Sprintf("action %v failed", event.ActionName), "ACTION_FAILED", response)
+		}
+		return response, err
+	})
+	if err != nil {
+		return result, toInvokeError(err)
+	}
+	return result, nil
+}
+
+func (l *LambdaInvocation) call(ctx context.Context, payload interface{}) (int, interface{}, error) {
+	if l.LambdaContextHandlerFunc != nil {
+		return l.LambdaContextHandlerFunc(ctx, payload)
+	}
+	return l.LambdaHandlerFunc(payload)
+}
+
+func parseLambdaEvent(request interface{}) *LambdaEvent {
+	event := &LambdaEvent{}
+	var blob []byte
+	var err error
+	switch v := request.(type) {
+	case LambdaEvent:
+		return &v
+	case *LambdaEvent:
+		return v
+	case string:
+		blob = []byte(v)
+	case []byte:
+		blob = v
+	case json.RawMessage:
+		blob = v
+	default:
+		blob, err = json.Marshal(v)
+		if err != nil {
+			panic(utils.NewHTTPBadRequestError(fmt.Sprintf("invalid lambda event : %v", err), nil))
+		}
+	}
+	err = json.Unmarshal(blob, event)
+	if err != nil {
+		panic(utils.NewHTTPBadRequestError(fmt.Sprintf("invalid lambda event : %v", err), string(blob)))
+	}
+	return event
+}
+
+func toInvokeError(err error) error {
+	errorType := "INTERNAL_SERVER_ERROR"
+	message := err.Error()
+	if custErr, ok := err.(*utils.Error); ok {
+		errorType = custErr.ErrorCode
+		message = custErr.ErrorMessage
+	}
+	return messages.InvokeResponse_Error{Message: message, Type: errorType}
+}
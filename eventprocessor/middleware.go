@@ -0,0 +1,332 @@
+package eventprocessor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/log"
+	"gobase-lambda/utils"
+)
+
+// Invocation describes a single handler invocation for the middlewares. Event is the trigger event,
+// for API events APIRequest is the request in the REST API form and API is the matched route, it is
+// nil until the route is resolved so only route middlewares can rely on it, like Principal. Ctx is
+// passed to the handlers, middlewares can replace it to add values.
+type Invocation struct {
+	Ctx           context.Context
+	EventType     EventType
+	Event         interface{}
+	APIRequest    *events.APIGatewayProxyRequest
+	API           *API
+	Principal     *Principal
+	Log           *log.Log
+	renderError   ErrorRenderer
+	timeoutMargin time.Duration
+}
+
+type Next func(invocation *Invocation) (interface{}, error)
+
+// Middleware wraps a handler invocation, it can act before and after calling next or return without
+// calling it. The result of API, SNS, SQS, S3 and cron invocations is an events.APIGatewayProxyResponse.
+type Middleware interface {
+	Handle(invocation *Invocation, next Next) (interface{}, error)
+}
+
+type MiddlewareFunc func(invocation *Invocation, next Next) (interface{}, error)
+
+func (f MiddlewareFunc) Handle(invocation *Invocation, next Next) (interface{}, error) {
+	return f(invocation, next)
+}
+
+// PanicError is returned by RecoveryMiddleware for panics which are not a *utils.Error.
+type PanicError struct {
+	Value interface{}
+	Stack string
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%v", e.Value)
+}
+
+// Built in middlewares, DefaultMiddleware returns them in the order used by the Handler.
+var (
+	// CorrelationMiddleware sets the correlation params of the logger from the API request headers.
+	CorrelationMiddleware Middleware = MiddlewareFunc(setCorrelation)
+	// RequestLoggingMiddleware logs the request and the response with the body and the
+	// authorization and cookie headers redacted.
+	RequestLoggingMiddleware Middleware = MiddlewareFunc(logRequest)
+	// ErrorMappingMiddleware converts the error of API invocations into the error response with the
+	// ErrorRenderer of the Handler, the error of other triggers is logged and kept for Lambda.
+	ErrorMappingMiddleware Middleware = MiddlewareFunc(mapError)
+	// RecoveryMiddleware converts panics into errors and sends the error notification for the
+	// unexpected ones.
+	RecoveryMiddleware Middleware = MiddlewareFunc(recoverPanic)
+	// TimeoutMiddleware cancels the ctx of the invocation the timeout margin before the Lambda
+	// timeout and returns a 504 error without waiting for the handler.
+	TimeoutMiddleware Middleware = MiddlewareFunc(cancelBeforeTimeout)
+)
+
+func DefaultMiddleware() []Middleware {
+	return []Middleware{CorrelationMiddleware, RequestLoggingMiddleware, ErrorMappingMiddleware, RecoveryMiddleware, TimeoutMiddleware}
+}
+
+// SetBuiltinMiddleware replaces the built in middlewares, it can be used to reorder them, drop some
+// or replace them with custom ones.
+//
+//	handler.SetBuiltinMiddleware(eventprocessor.CorrelationMiddleware, metrics, eventprocessor.ErrorMappingMiddleware, eventprocessor.RecoveryMiddleware)
+func (h *Handler) SetBuiltinMiddleware(middleware ...Middleware) {
+	h.builtinMiddleware = middleware
+}
+
+// Use adds middlewares for all the event types, they run after the built in ones.
+func (h *Handler) Use(middleware ...Middleware) {
+	h.middleware = append(h.middleware, middleware...)
+}
+
+// UseFor adds middlewares for a single event type, they run after the global ones. API routes can
+// add their own middlewares with API.Middleware.
+func (h *Handler) UseFor(eventType EventType, middleware ...Middleware) {
+	if h.eventMiddleware == nil {
+		h.eventMiddleware = make(map[EventType][]Middleware)
+	}
+	h.eventMiddleware[eventType] = append(h.eventMiddleware[eventType], middleware...)
+}
+
+func (h *Handler) newInvocation(ctx context.Context, eventType EventType, event interface{}) *Invocation {
+	renderError := h.errorRenderer
+	if renderError == nil {
+		renderError = RenderProblem
+	}
+	return &Invocation{
+		Ctx:           context.WithValue(ctx, loggerContextKey, h.log),
+		EventType:     eventType,
+		Event:         event,
+		Log:           h.log,
+		renderError:   renderError,
+		timeoutMargin: h.timeoutMargin,
+	}
+}
+
+// invoke runs the handler through the built in, global and event type middlewares.
+func (h *Handler) invoke(invocation *Invocation, handler Next) (interface{}, error) {
+	builtinMiddleware := h.builtinMiddleware
+	if builtinMiddleware == nil {
+		builtinMiddleware = DefaultMiddleware()
+	}
+	middlewares := make([]Middleware, 0, len(builtinMiddleware)+len(h.middleware)+len(h.eventMiddleware[invocation.EventType]))
+	middlewares = append(append(append(middlewares, builtinMiddleware...), h.middleware...), h.eventMiddleware[invocation.EventType]...)
+	return chainMiddleware(middlewares, handler)(invocation)
+}
+
+// chainMiddleware wraps next with the middlewares, the first one is the outermost.
+func chainMiddleware(middlewares []Middleware, next Next) Next {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		middleware, inner := middlewares[i], next
+		next = func(invocation *Invocation) (interface{}, error) {
+			return middleware.Handle(invocation, inner)
+		}
+	}
+	return next
+}
+
+func setCorrelation(invocation *Invocation, next Next) (interface{}, error) {
+	correlationParams := map[string]string{}
+	if invocation.APIRequest != nil && invocation.APIRequest.Headers != nil {
+		correlationParams = invocation.APIRequest.Headers
+	}
+	invocation.Log.SetCorrelationParams(correlationParams)
+	invocation.Ctx = context.WithValue(invocation.Ctx, correlationContextKey, invocation.Log.GetCorrelationParams())
+	return next(invocation)
+}
+
+func logRequest(invocation *Invocation, next Next) (interface{}, error) {
+	logger := invocation.Log
+	logger.Debug("Full Request", invocationRequest(invocation))
+	if invocation.APIRequest != nil {
+		printReducedRequest(logger, invocation.APIRequest, true)
+	}
+	result, err := next(invocation)
+	if err != nil {
+		logger.Error("Error", err)
+		return result, err
+	}
+	res, ok := result.(events.APIGatewayProxyResponse)
+	switch {
+	case !ok || invocation.APIRequest == nil:
+		logger.Info("Full Response", result)
+	case res.StatusCode > 299:
+		printReducedRequest(logger, invocation.APIRequest, false)
+		logger.Info("Response", res)
+	default:
+		logger.Debug("Full Response", res)
+		body := res.Body
+		res.Body = "<<<redacted>>>"
+		logger.Info("Response", res)
+		res.Body = body
+	}
+	return result, err
+}
+
+func mapError(invocation *Invocation, next Next) (interface{}, error) {
+	result, err := next(invocation)
+	if err == nil {
+		return result, nil
+	}
+	if _, ok := err.(*PanicError); !ok {
+		invocation.Log.Error("Full Request", invocationRequest(invocation))
+		invocation.Log.Error(fmt.Sprintf("%v Error", invocation.EventType), err)
+	}
+	if invocation.APIRequest == nil {
+		return result, err
+	}
+	return invocation.errorResponse(err), nil
+}
+
+func recoverPanic(invocation *Invocation, next Next) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr, ok := r.(*PanicError)
+			if !ok {
+				panicErr = &PanicError{Value: r, Stack: string(debug.Stack())}
+			}
+			invocation.Log.Error("Full Request", invocationRequest(invocation))
+			invocation.Log.Error("Panic Stack", panicErr.Stack)
+			invocation.Log.Error("Panic Recovery", panicErr.Value)
+			if custErr, ok := panicErr.Value.(*utils.Error); ok {
+				err = custErr
+				return
+			}
+			err = panicErr
+			notifyError(invocation.Log, panicErr.Value)
+		}
+	}()
+	return next(invocation)
+}
+
+// cancelBeforeTimeout runs the invocation in a goroutine with the deadline of the ctx moved before the
+// Lambda timeout. Panics are passed back to the invocation goroutine as a *PanicError with their stack.
+func cancelBeforeTimeout(invocation *Invocation, next Next) (interface{}, error) {
+	deadline, ok := invocation.Ctx.Deadline()
+	if !ok {
+		return next(invocation)
+	}
+	margin := invocation.timeoutMargin
+	if margin <= 0 {
+		margin = defaultTimeoutMargin
+	}
+	ctx, cancel := context.WithDeadline(invocation.Ctx, deadline.Add(-margin))
+	defer cancel()
+	// the handler gets a copy of the invocation, its request and logger as it can keep running after
+	// the timeout while the response is logged and the next invocation starts
+	inner := *invocation
+	logger := *invocation.Log
+	inner.Log = &logger
+	inner.Ctx = context.WithValue(ctx, loggerContextKey, inner.Log)
+	if invocation.APIRequest != nil {
+		inner.APIRequest = copyRequest(invocation.APIRequest)
+	}
+	type outcome struct {
+		result   interface{}
+		err      error
+		panicErr *PanicError
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{panicErr: &PanicError{Value: r, Stack: string(debug.Stack())}}
+			}
+		}()
+		result, err := next(&inner)
+		done <- outcome{result: result, err: err}
+	}()
+	select {
+	case o := <-done:
+		inner.Ctx, inner.Log, inner.APIRequest = invocation.Ctx, invocation.Log, invocation.APIRequest
+		*invocation = inner
+		if o.panicErr != nil {
+			panic(o.panicErr)
+		}
+		return o.result, o.err
+	case <-ctx.Done():
+		invocation.Log.Error("Invocation Timeout", fmt.Sprintf("%v invocation was canceled %v before the Lambda timeout", invocation.EventType, margin))
+		return nil, utils.NewError(http.StatusGatewayTimeout, "request timed out", "TIMEOUT", nil)
+	}
+}
+
+// copyRequest copies the maps of the request so that the handler goroutine doesn't share them.
+func copyRequest(request *events.APIGatewayProxyRequest) *events.APIGatewayProxyRequest {
+	requestCopy := *request
+	requestCopy.Headers = copyStringMap(request.Headers)
+	requestCopy.MultiValueHeaders = copyStringsMap(request.MultiValueHeaders)
+	requestCopy.QueryStringParameters = copyStringMap(request.QueryStringParameters)
+	requestCopy.MultiValueQueryStringParameters = copyStringsMap(request.MultiValueQueryStringParameters)
+	requestCopy.PathParameters = copyStringMap(request.PathParameters)
+	requestCopy.StageVariables = copyStringMap(request.StageVariables)
+	if request.RequestContext.Authorizer != nil {
+		requestCopy.RequestContext.Authorizer = make(map[string]interface{}, len(request.RequestContext.Authorizer))
+		for key, value := range request.RequestContext.Authorizer {
+			requestCopy.RequestContext.Authorizer[key] = value
+		}
+	}
+	return &requestCopy
+}
+
+func copyStringMap(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	valuesCopy := make(map[string]string, len(values))
+	for key, value := range values {
+		valuesCopy[key] = value
+	}
+	return valuesCopy
+}
+
+func copyStringsMap(values map[string][]string) map[string][]string {
+	if values == nil {
+		return nil
+	}
+	valuesCopy := make(map[string][]string, len(values))
+	for key, value := range values {
+		valuesCopy[key] = append([]string(nil), value...)
+	}
+	return valuesCopy
+}
+
+// invocationRequest returns the request to log, the credential headers of API requests are redacted.
+func invocationRequest(invocation *Invocation) interface{} {
+	if invocation.APIRequest != nil {
+		return redactRequest(invocation.APIRequest)
+	}
+	return invocation.Event
+}
+
+// errorResponse renders the error of the invocation, RenderProblem is used for invocations not
+// created by the Handler.
+func (i *Invocation) errorResponse(err error) events.APIGatewayProxyResponse {
+	if i.renderError == nil {
+		return RenderProblem(i, err)
+	}
+	return i.renderError(i, err)
+}
+
+// proxyResponse converts the result of the middleware chain, an error left by a custom chain is
+// mapped to the error response.
+func proxyResponse(invocation *Invocation, result interface{}, err error) events.APIGatewayProxyResponse {
+	if err != nil {
+		return invocation.errorResponse(err)
+	}
+	res, _ := result.(events.APIGatewayProxyResponse)
+	return res
+}
+
+// triggerResponse converts the result of the middleware chain of a non API trigger, the error is
+// returned to Lambda as well so that the retries and the DLQ of the trigger apply.
+func triggerResponse(invocation *Invocation, result interface{}, err error) (events.APIGatewayProxyResponse, error) {
+	return proxyResponse(invocation, result, err), err
+}
@@ -0,0 +1,239 @@
+package eventprocessor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/gabriel-vasile/mimetype"
+	"gobase-lambda/utils"
+)
+
+const MediaTypeMultipart = "multipart/form-data"
+
+// maxLambdaPayloadSize is the request payload limit of synchronous Lambda invocations.
+const maxLambdaPayloadSize = 6 * 1024 * 1024
+
+var (
+	filePartType  = reflect.TypeOf(&FilePart{})
+	filePartsType = reflect.TypeOf([]*FilePart{})
+)
+
+// MultipartLimits restricts the files of multipart/form-data bodies. MaxFileSize applies to every file,
+// AllowedTypes are matched against the MIME type detected from the content, "image/*" like patterns are
+// supported. Zero values mean no limit, but the body is always bounded by the Lambda payload limit.
+type MultipartLimits struct {
+	MaxFileSize  int64
+	MaxFiles     int
+	AllowedTypes []string
+}
+
+// MultipartForm is a parsed multipart/form-data body. Register it as the Body of a route to get the
+// whole form, or use a struct whose *FilePart and []*FilePart fields are bound to the file parts and
+// the other fields to the form values, like query string params.
+//
+//	type DocumentUpload struct {
+//		DocumentType string                   `json:"documentType" validate:"required"`
+//		File         *eventprocessor.FilePart `json:"file" validate:"required"`
+//	}
+type MultipartForm struct {
+	Values map[string][]string
+	Files  map[string][]*FilePart
+}
+
+func (f *MultipartForm) Value(name string) string {
+	if values := f.Values[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (f *MultipartForm) File(name string) *FilePart {
+	if files := f.Files[name]; len(files) > 0 {
+		return files[0]
+	}
+	return nil
+}
+
+// FilePart is an uploaded file. ContentType is detected from the content, DeclaredContentType is the
+// one sent by the client.
+type FilePart struct {
+	FieldName           string               `json:"fieldName"`
+	FileName            string               `json:"fileName"`
+	Size                int64                `json:"size"`
+	ContentType         string               `json:"contentType"`
+	DeclaredContentType string               `json:"declaredContentType"`
+	Header              textproto.MIMEHeader `json:"-"`
+	data                []byte
+}
+
+// Open returns a reader of the file, it can be passed as is to aws.S3.PutObject and aws.S3PII.PutObject.
+//
+//	err := s3Client.PutObject(bucket, key, file.Open(), file.ContentType)
+func (p *FilePart) Open() io.ReadSeeker {
+	return bytes.NewReader(p.data)
+}
+
+func (p *FilePart) Bytes() []byte {
+	return p.data
+}
+
+// UnmarshalJSON fails as files are only accepted from multipart/form-data bodies, where MultipartLimits
+// are checked.
+func (p *FilePart) UnmarshalJSON([]byte) error {
+	return errors.New("files can only be uploaded as multipart/form-data")
+}
+
+// ParseMultipartForm parses a multipart/form-data body, contentType is the Content-Type header of the
+// request carrying the boundary. Limit violations are returned as *utils.Error with status 413 or 415.
+func ParseMultipartForm(contentType string, body []byte, limits *MultipartLimits) (*MultipartForm, error) {
+	if limits == nil {
+		limits = &MultipartLimits{}
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.EqualFold(mediaType, MediaTypeMultipart) || params["boundary"] == "" {
+		return nil, utils.NewHTTPBadRequestError("invalid multipart content type", contentType)
+	}
+	form := &MultipartForm{Values: map[string][]string{}, Files: map[string][]*FilePart{}}
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	fileCount := 0
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return form, nil
+		}
+		if err != nil {
+			return nil, utils.NewHTTPBadRequestError(fmt.Sprintf("multipart body parsing failed : %v", err), nil)
+		}
+		name := part.FormName()
+		if part.FileName() == "" {
+			value, err := io.ReadAll(part)
+			if err != nil {
+				return nil, utils.NewHTTPBadRequestError(fmt.Sprintf("multipart body parsing failed : %v", err), nil)
+			}
+			form.Values[name] = append(form.Values[name], string(value))
+			continue
+		}
+		fileCount++
+		if limits.MaxFiles > 0 && fileCount > limits.MaxFiles {
+			return nil, utils.NewHTTPBadRequestError(fmt.Sprintf("at most %v files can be uploaded", limits.MaxFiles), nil)
+		}
+		filePart, err := readFilePart(part, limits)
+		if err != nil {
+			return nil, err
+		}
+		form.Files[name] = append(form.Files[name], filePart)
+	}
+}
+
+func readFilePart(part *multipart.Part, limits *MultipartLimits) (*FilePart, error) {
+	maxFileSize := limits.MaxFileSize
+	if maxFileSize <= 0 {
+		maxFileSize = maxLambdaPayloadSize
+	}
+	data, err := io.ReadAll(io.LimitReader(part, maxFileSize+1))
+	if err != nil {
+		return nil, utils.NewHTTPBadRequestError(fmt.Sprintf("multipart body parsing failed : %v", err), nil)
+	}
+	if int64(len(data)) > maxFileSize {
+		return nil, utils.NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("file %v exceeds %v bytes", part.FileName(), maxFileSize), "FILE_TOO_LARGE", map[string]string{
+			"field":    part.FormName(),
+			"fileName": part.FileName(),
+		})
+	}
+	detected := mimetype.Detect(data)
+	filePart := &FilePart{
+		FieldName:           part.FormName(),
+		FileName:            part.FileName(),
+		Size:                int64(len(data)),
+		ContentType:         parseMediaType(detected.String()),
+		DeclaredContentType: part.Header.Get("Content-Type"),
+		Header:              part.Header,
+		data:                data,
+	}
+	if len(limits.AllowedTypes) > 0 && !isAllowedFileType(detected, limits.AllowedTypes) {
+		return nil, utils.NewError(http.StatusUnsupportedMediaType, fmt.Sprintf("file %v of type %v is not allowed", part.FileName(), filePart.ContentType), "UNSUPPORTED_FILE_TYPE", map[string]string{
+			"field":       part.FormName(),
+			"fileName":    part.FileName(),
+			"contentType": filePart.ContentType,
+		})
+	}
+	return filePart, nil
+}
+
+func isAllowedFileType(detected *mimetype.MIME, allowedTypes []string) bool {
+	for _, allowed := range allowedTypes {
+		if strings.HasSuffix(allowed, "/*") {
+			if strings.HasPrefix(detected.String(), strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+		} else if detected.Is(allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMultipartBody reports whether the body type is a MultipartForm or has FilePart fields, these bodies
+// are only bound from multipart/form-data requests so that the file limits always apply.
+func isMultipartBody(bodyType reflect.Type) bool {
+	for bodyType.Kind() == reflect.Ptr {
+		bodyType = bodyType.Elem()
+	}
+	if bodyType == multipartFormType {
+		return true
+	}
+	if bodyType.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < bodyType.NumField(); i++ {
+		if fieldType := bodyType.Field(i).Type; fieldType == filePartType || fieldType == filePartsType {
+			return true
+		}
+	}
+	return false
+}
+
+// getMultipartBody parses the body and binds it to the Body registered on the API.
+func getMultipartBody(apiMap *API, request *events.APIGatewayProxyRequest, requestBody []byte, body interface{}) interface{} {
+	form, err := ParseMultipartForm(getHeader(request.Headers, "Content-Type"), requestBody, apiMap.Multipart)
+	if err != nil {
+		panic(err)
+	}
+	if target, ok := body.(*MultipartForm); ok {
+		*target = *form
+		return target
+	}
+	target := reflect.ValueOf(body)
+	if target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Struct {
+		panic(utils.NewError(http.StatusUnsupportedMediaType, fmt.Sprintf("content type %v is not supported", MediaTypeMultipart), "UNSUPPORTED_MEDIA_TYPE", nil))
+	}
+	fieldErrors := bindQueryParams(target.Elem(), map[string]string{}, form.Values)
+	for _, fieldError := range fieldErrors {
+		fieldError.In = paramInBody
+	}
+	targetType := target.Elem().Type()
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		files := form.Files[queryParamName(field)]
+		switch {
+		case !field.IsExported() || len(files) == 0:
+		case field.Type == filePartType:
+			target.Elem().Field(i).Set(reflect.ValueOf(files[0]))
+		case field.Type == filePartsType:
+			target.Elem().Field(i).Set(reflect.ValueOf(files))
+		}
+	}
+	if len(fieldErrors) > 0 {
+		panic(utils.NewError(http.StatusBadRequest, "request validation failed", "VALIDATION_FAILED", fieldErrors))
+	}
+	return body
+}
@@ -0,0 +1,510 @@
+package eventprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	openAPIVersion = "3.1.0"
+	// OpenAPIResource is the route registered by ServeOpenAPI.
+	OpenAPIResource = "/openapi.json"
+)
+
+const bearerAuthScheme = "bearerAuth"
+
+var (
+	fileResponseType   = reflect.TypeOf(FileResponse{})
+	proxyResponseType  = reflect.TypeOf(events.APIGatewayProxyResponse{})
+	multipartFormType  = reflect.TypeOf(MultipartForm{})
+	problemType        = reflect.TypeOf(Problem{})
+	schemaNameReplacer = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIDocument is an OpenAPI 3.1 document, only the parts generated from the routes are modelled.
+type OpenAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Servers    []*OpenAPIServer                        `json:"servers,omitempty"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components"`
+}
+
+type OpenAPIServer struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]*OpenAPISchema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*OpenAPISecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type OpenAPISecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	OperationId string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+	Security    []map[string][]string       `json:"security,omitempty"`
+}
+
+type OpenAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required,omitempty"`
+	Schema   *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                         `json:"required,omitempty"`
+	Content  map[string]*OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema,omitempty"`
+}
+
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	ContentMediaType     string                    `json:"contentMediaType,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}             `json:"enum,omitempty"`
+	Minimum              *float64                  `json:"minimum,omitempty"`
+	Maximum              *float64                  `json:"maximum,omitempty"`
+	MinLength            *int                      `json:"minLength,omitempty"`
+	MaxLength            *int                      `json:"maxLength,omitempty"`
+	MinItems             *int                      `json:"minItems,omitempty"`
+	MaxItems             *int                      `json:"maxItems,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+	Examples             []interface{}             `json:"examples,omitempty"`
+}
+
+// WriteFile writes the document as indented JSON.
+func (d *OpenAPIDocument) WriteFile(path string) error {
+	blob, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, blob, 0644)
+}
+
+// OpenAPI generates the OpenAPI document of the routes returned by GetAPIHandler.
+//
+//	err := handler.OpenAPI(eventprocessor.OpenAPIInfo{Title: "Customer API", Version: "1.0.0"}).WriteFile("openapi.json")
+func (h *Handler) OpenAPI(info OpenAPIInfo) *OpenAPIDocument {
+	eventProcessor := h.eventProcessorFunc(context.Background(), h.log, nil, EventAPI)
+	return GenerateOpenAPI(eventProcessor.GetAPIHandler(), info)
+}
+
+// ServeOpenAPI registers GET /openapi.json answering the OpenAPI document of the routes, unless the
+// service registers the resource itself. The resource has to be configured on API Gateway as well.
+func (h *Handler) ServeOpenAPI(info OpenAPIInfo) {
+	h.openAPI = &info
+}
+
+// withOpenAPIRoute adds the OpenAPI route to a copy of the routes, the document is generated once.
+func (h *Handler) withOpenAPIRoute(apiMap map[string]map[string]*API) map[string]map[string]*API {
+	if h.openAPI == nil || apiMap[OpenAPIResource][http.MethodGet] != nil {
+		return apiMap
+	}
+	h.openAPIOnce.Do(func() {
+		h.openAPIBody, h.openAPIErr = json.Marshal(GenerateOpenAPI(apiMap, *h.openAPI))
+	})
+	routes := make(map[string]map[string]*API, len(apiMap)+1)
+	for resource, methods := range apiMap {
+		routes[resource] = methods
+	}
+	methods := map[string]*API{http.MethodGet: {
+		Resource: OpenAPIResource,
+		Method:   http.MethodGet,
+		ApiHandler: func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+			if h.openAPIErr != nil {
+				return http.StatusInternalServerError, nil, h.openAPIErr
+			}
+			return http.StatusOK, events.APIGatewayProxyResponse{
+				StatusCode: http.StatusOK,
+				Headers:    map[string]string{"Content-Type": MediaTypeJSON},
+				Body:       string(h.openAPIBody),
+			}, nil
+		},
+	}}
+	for method, api := range apiMap[OpenAPIResource] {
+		methods[method] = api
+	}
+	routes[OpenAPIResource] = methods
+	return routes
+}
+
+// GenerateOpenAPI builds the OpenAPI document of the routes from the Body, QueryParams and PathParams
+// types, the json and structs tags name the fields and the validate tags become schema constraints.
+// Routes created with Route document the response type as well.
+func GenerateOpenAPI(apiMap map[string]map[string]*API, info OpenAPIInfo) *OpenAPIDocument {
+	generator := &openAPIGenerator{schemas: make(map[string]*OpenAPISchema), names: make(map[reflect.Type]string)}
+	generator.schemaName(problemType)
+	document := &OpenAPIDocument{
+		OpenAPI:    openAPIVersion,
+		Info:       info,
+		Paths:      make(map[string]map[string]*OpenAPIOperation),
+		Components: OpenAPIComponents{Schemas: generator.schemas},
+	}
+	resources := make([]string, 0, len(apiMap))
+	for resource := range apiMap {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	for _, resource := range resources {
+		methods := make([]string, 0, len(apiMap[resource]))
+		for method := range apiMap[resource] {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		path := strings.ReplaceAll(resource, "+}", "}")
+		for _, method := range methods {
+			api := apiMap[resource][method]
+			if api == nil {
+				continue
+			}
+			if document.Paths[path] == nil {
+				document.Paths[path] = make(map[string]*OpenAPIOperation)
+			}
+			document.Paths[path][strings.ToLower(method)] = generator.operation(resource, method, api)
+			if len(api.Scopes) > 0 || len(api.Groups) > 0 {
+				document.Components.SecuritySchemes = map[string]*OpenAPISecurityScheme{
+					bearerAuthScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				}
+			}
+		}
+	}
+	return document
+}
+
+type openAPIGenerator struct {
+	schemas map[string]*OpenAPISchema
+	names   map[reflect.Type]string
+}
+
+func (g *openAPIGenerator) operation(resource, method string, api *API) *OpenAPIOperation {
+	operation := &OpenAPIOperation{
+		OperationId: operationId(method, resource),
+		Summary:     api.Summary,
+		Tags:        api.Tags,
+		Parameters:  make([]*OpenAPIParameter, 0),
+		Responses:   make(map[string]*OpenAPIResponse),
+	}
+	pathParams, _ := parseResource(resource)
+	pathFields := map[string]reflect.StructField{}
+	if api.PathParams != nil {
+		pathFields, _ = bindingFields(api.PathParams, fieldName)
+	}
+	for _, name := range pathParams {
+		schema := &OpenAPISchema{Type: "string"}
+		if field, ok := pathFields[strings.ToLower(name)]; ok && isPathParamType(field.Type) {
+			schema = g.fieldSchema(field)
+		}
+		operation.Parameters = append(operation.Parameters, &OpenAPIParameter{Name: name, In: paramInPath, Required: true, Schema: schema})
+	}
+	if api.QueryParams != nil {
+		queryFields, _ := bindingFields(api.QueryParams, queryParamName)
+		names := make([]string, 0, len(queryFields))
+		for key := range queryFields {
+			names = append(names, key)
+		}
+		sort.Strings(names)
+		for _, key := range names {
+			field := queryFields[key]
+			operation.Parameters = append(operation.Parameters, &OpenAPIParameter{
+				Name:     queryParamName(field),
+				In:       paramInQuery,
+				Required: isRequiredField(field),
+				Schema:   g.fieldSchema(field),
+			})
+		}
+	}
+	if api.Body != nil && method != http.MethodGet {
+		bodyType := reflect.TypeOf(api.Body)
+		mediaType := MediaTypeJSON
+		if api.Multipart != nil || isMultipartBody(bodyType) {
+			mediaType = MediaTypeMultipart
+		}
+		operation.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content:  map[string]*OpenAPIMediaType{mediaType: {Schema: g.schema(bodyType)}},
+		}
+	}
+	operation.Responses[strconv.Itoa(http.StatusOK)] = g.successResponse(api.responseType)
+	errorStatuses := []int{http.StatusInternalServerError}
+	if len(operation.Parameters) > 0 || operation.RequestBody != nil {
+		errorStatuses = append(errorStatuses, http.StatusBadRequest)
+	}
+	if len(api.Scopes) > 0 || len(api.Groups) > 0 {
+		scopes := append([]string{}, api.Scopes...)
+		operation.Security = []map[string][]string{{bearerAuthScheme: scopes}}
+		errorStatuses = append(errorStatuses, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	if operation.RequestBody != nil && operation.RequestBody.Content[MediaTypeMultipart] != nil {
+		errorStatuses = append(errorStatuses, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType)
+	}
+	for _, status := range errorStatuses {
+		operation.Responses[strconv.Itoa(status)] = &OpenAPIResponse{
+			Description: http.StatusText(status),
+			Content:     map[string]*OpenAPIMediaType{MediaTypeProblem: {Schema: &OpenAPISchema{Ref: "#/components/schemas/" + g.schemaName(problemType)}}},
+		}
+	}
+	return operation
+}
+
+func (g *openAPIGenerator) successResponse(responseType reflect.Type) *OpenAPIResponse {
+	response := &OpenAPIResponse{Description: http.StatusText(http.StatusOK)}
+	if responseType == nil {
+		return response
+	}
+	for responseType.Kind() == reflect.Ptr {
+		responseType = responseType.Elem()
+	}
+	switch {
+	case responseType == proxyResponseType || responseType.Kind() == reflect.Interface:
+	case responseType == fileResponseType:
+		response.Content = map[string]*OpenAPIMediaType{"application/octet-stream": {
+			Schema: &OpenAPISchema{Type: "string", ContentMediaType: "application/octet-stream"},
+		}}
+	default:
+		response.Content = map[string]*OpenAPIMediaType{MediaTypeJSON: {Schema: g.schema(responseType)}}
+	}
+	return response
+}
+
+func (g *openAPIGenerator) schema(valueType reflect.Type) *OpenAPISchema {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	switch {
+	case valueType == filePartType.Elem():
+		return &OpenAPISchema{Type: "string", ContentMediaType: "application/octet-stream"}
+	case valueType == multipartFormType:
+		return &OpenAPISchema{Type: "object"}
+	case valueType == timeType:
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	case valueType == durationType:
+		return &OpenAPISchema{Type: "string", Examples: []interface{}{"1h30m"}}
+	case reflect.PointerTo(valueType).Implements(textUnmarshalerType):
+		return &OpenAPISchema{Type: "string"}
+	}
+	switch valueType.Kind() {
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if valueType.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: g.schema(valueType.Elem())}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: g.schema(valueType.Elem())}
+	case reflect.Struct:
+		if valueType.Name() == "" {
+			return g.structSchema(valueType)
+		}
+		return &OpenAPISchema{Ref: "#/components/schemas/" + g.schemaName(valueType)}
+	}
+	return &OpenAPISchema{}
+}
+
+// schemaName registers the struct in the components, the package name is added on a name clash.
+func (g *openAPIGenerator) schemaName(valueType reflect.Type) string {
+	if name, ok := g.names[valueType]; ok {
+		return name
+	}
+	name := schemaNameReplacer.ReplaceAllString(valueType.Name(), "_")
+	if _, ok := g.schemas[name]; ok {
+		packagePath := strings.Split(valueType.PkgPath(), "/")
+		name = schemaNameReplacer.ReplaceAllString(packagePath[len(packagePath)-1], "_") + "." + name
+	}
+	for base, suffix := name, 2; g.schemas[name] != nil; suffix++ {
+		name = fmt.Sprintf("%v%v", base, suffix)
+	}
+	g.names[valueType] = name
+	g.schemas[name] = &OpenAPISchema{}
+	*g.schemas[name] = *g.structSchema(valueType)
+	return name
+}
+
+func (g *openAPIGenerator) structSchema(valueType reflect.Type) *OpenAPISchema {
+	schema := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+	g.addProperties(schema, valueType)
+	return schema
+}
+
+// addProperties adds the fields like encoding/json does, embedded structs without a name are flattened.
+func (g *openAPIGenerator) addProperties(schema *OpenAPISchema, valueType reflect.Type) {
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			g.addProperties(schema, fieldType)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name = fieldName(field); name == "-" {
+			continue
+		}
+		schema.Properties[name] = g.fieldSchema(field)
+		if isRequiredField(field) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// fieldSchema is the schema of the field type with the constraints of its validate tag.
+func (g *openAPIGenerator) fieldSchema(field reflect.StructField) *OpenAPISchema {
+	schema := g.schema(field.Type)
+	tag, ok := field.Tag.Lookup("validate")
+	if !ok {
+		return schema
+	}
+	for _, rule := range splitRules(tag) {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "min", "max":
+			bound, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			setBound(schema, bound, name == "min")
+		case "len":
+			length, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			setBound(schema, float64(length), true)
+			setBound(schema, float64(length), false)
+		case "enum":
+			target := schema
+			if schema.Type == "array" {
+				target = schema.Items
+			}
+			for _, value := range strings.Split(param, "|") {
+				target.Enum = append(target.Enum, enumValue(target.Type, value))
+			}
+		case "email":
+			schema.Format = "email"
+		case "regex":
+			schema.Pattern = param
+		}
+	}
+	return schema
+}
+
+func setBound(schema *OpenAPISchema, bound float64, isMin bool) {
+	length := int(bound)
+	switch schema.Type {
+	case "string":
+		if isMin {
+			schema.MinLength = &length
+		} else {
+			schema.MaxLength = &length
+		}
+	case "array", "object":
+		if isMin {
+			schema.MinItems = &length
+		} else {
+			schema.MaxItems = &length
+		}
+	case "integer", "number":
+		if isMin {
+			schema.Minimum = &bound
+		} else {
+			schema.Maximum = &bound
+		}
+	}
+}
+
+func enumValue(schemaType, value string) interface{} {
+	switch schemaType {
+	case "integer":
+		if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return number
+		}
+	case "number":
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case "boolean":
+		if flag, err := strconv.ParseBool(value); err == nil {
+			return flag
+		}
+	}
+	return value
+}
+
+func isRequiredField(field reflect.StructField) bool {
+	return containsString(splitRules(field.Tag.Get("validate")), "required")
+}
+
+// operationId is built from the method and the resource, GET /customers/{customerId} is
+// getCustomersByCustomerId.
+func operationId(method, resource string) string {
+	id := strings.ToLower(method)
+	for _, segment := range splitPath(resource) {
+		if strings.HasPrefix(segment, "{") {
+			segment = "by-" + strings.Trim(segment, "{}+")
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+		}) {
+			id += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return id
+}
@@ -0,0 +1,199 @@
+package eventprocessor
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/utils"
+)
+
+const (
+	PrincipalSourceCognito = "COGNITO"
+	PrincipalSourceJWT     = "JWT"
+	PrincipalSourceLambda  = "LAMBDA"
+	PrincipalSourceBearer  = "BEARER"
+)
+
+// Principal is the authenticated caller of an API request. Claims holds all the claims of the token,
+// or the context of a Lambda authorizer.
+type Principal struct {
+	Source   string
+	Subject  string
+	Username string
+	Email    string
+	Scopes   []string
+	Groups   []string
+	Claims   map[string]interface{}
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return containsString(p.Scopes, scope)
+}
+
+func (p *Principal) InGroup(group string) bool {
+	return containsString(p.Groups, group)
+}
+
+// GetPrincipal extracts the principal from the authorizer of the request, Cognito user pool and JWT
+// authorizers put the token claims under "claims", anything else is taken as a Lambda authorizer
+// context. nil is returned when the request has no authorizer.
+func GetPrincipal(request *events.APIGatewayProxyRequest) *Principal {
+	authorizer := request.RequestContext.Authorizer
+	if len(authorizer) == 0 {
+		return nil
+	}
+	if claims, ok := authorizer["claims"]; ok {
+		principal := newPrincipal(PrincipalSourceJWT, toClaims(claims))
+		if strings.Contains(stringClaim(principal.Claims, "iss"), "cognito-idp") {
+			principal.Source = PrincipalSourceCognito
+		}
+		if scopes, ok := authorizer["scopes"]; ok && len(principal.Scopes) == 0 {
+			principal.Scopes = listClaim(scopes)
+		}
+		return principal
+	}
+	principal := newPrincipal(PrincipalSourceLambda, authorizer)
+	if principalId := stringClaim(authorizer, "principalId"); principalId != "" {
+		principal.Subject = principalId
+	}
+	if len(principal.Scopes) == 0 {
+		principal.Scopes = listClaim(authorizer["scopes"])
+	}
+	if len(principal.Groups) == 0 {
+		principal.Groups = listClaim(authorizer["groups"])
+	}
+	return principal
+}
+
+func newPrincipal(source string, claims map[string]interface{}) *Principal {
+	principal := &Principal{
+		Source:  source,
+		Subject: stringClaim(claims, "sub"),
+		Email:   stringClaim(claims, "email"),
+		Scopes:  listClaim(claims["scope"]),
+		Groups:  listClaim(claims["cognito:groups"]),
+		Claims:  claims,
+	}
+	for _, key := range []string{"cognito:username", "username", "preferred_username"} {
+		if principal.Username = stringClaim(claims, key); principal.Username != "" {
+			break
+		}
+	}
+	if len(principal.Scopes) == 0 {
+		principal.Scopes = listClaim(claims["scp"])
+	}
+	if len(principal.Groups) == 0 {
+		principal.Groups = listClaim(claims["groups"])
+	}
+	return principal
+}
+
+func toClaims(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v
+	case map[string]string:
+		claims := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			claims[key] = value
+		}
+		return claims
+	}
+	return map[string]interface{}{}
+}
+
+func stringClaim(claims map[string]interface{}, key string) string {
+	value, ok := claims[key]
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// listClaim reads a claim which holds a list, API Gateway passes lists of the claims as strings like
+// "[admin user]" and scopes are space separated.
+func listClaim(value interface{}) []string {
+	values := make([]string, 0)
+	switch v := value.(type) {
+	case []string:
+		values = append(values, v...)
+	case []interface{}:
+		for _, item := range v {
+			values = append(values, fmt.Sprintf("%v", item))
+		}
+	case string:
+		v = strings.TrimSuffix(strings.TrimPrefix(v, "["), "]")
+		values = strings.FieldsFunc(v, func(r rune) bool {
+			return r == ' ' || r == ','
+		})
+	}
+	return values
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePrincipal returns the principal of the request from the authorizer, or from the Bearer token
+// when a JWT verifier is set. The error of an invalid Bearer token is returned with a nil principal.
+func (h *Handler) resolvePrincipal(request *events.APIGatewayProxyRequest) (*Principal, error) {
+	if principal := GetPrincipal(request); principal != nil {
+		return principal, nil
+	}
+	if h.jwtVerifier == nil {
+		return nil, nil
+	}
+	token, ok := bearerToken(getHeader(request.Headers, "Authorization"))
+	if !ok {
+		return nil, nil
+	}
+	claims, err := h.jwtVerifier.Verify(token)
+	if err != nil {
+		h.log.Warning("JWT verification failed", err.Error())
+		return nil, err
+	}
+	return newPrincipal(PrincipalSourceBearer, claims), nil
+}
+
+func bearerToken(authorization string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(authorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// authorizeRoute checks the scopes and groups required by the route, all the scopes and any one of the
+// groups are required. Routes without requirements accept anonymous calls and invalid tokens.
+func authorizeRoute(api *API, principal *Principal, principalErr error) {
+	if len(api.Scopes) == 0 && len(api.Groups) == 0 {
+		return
+	}
+	if principalErr != nil {
+		panic(utils.NewError(http.StatusUnauthorized, "invalid token", "UNAUTHORIZED", nil))
+	}
+	if principal == nil {
+		panic(utils.NewError(http.StatusUnauthorized, "authentication required", "UNAUTHORIZED", nil))
+	}
+	for _, scope := range api.Scopes {
+		if !principal.HasScope(scope) {
+			panic(utils.NewError(http.StatusForbidden, fmt.Sprintf("scope %v is required", scope), "FORBIDDEN", nil))
+		}
+	}
+	if len(api.Groups) == 0 {
+		return
+	}
+	for _, group := range api.Groups {
+		if principal.InGroup(group) {
+			return
+		}
+	}
+	panic(utils.NewError(http.StatusForbidden, fmt.Sprintf("one of the groups %v is required", strings.Join(api.Groups, ", ")), "FORBIDDEN", nil))
+}
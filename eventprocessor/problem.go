@@ -0,0 +1,89 @@
+package eventprocessor
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/utils"
+)
+
+const MediaTypeProblem = "application/problem+json"
+
+const (
+	problemTypeBlank      = "about:blank"
+	internalErrorCode     = "INTERNAL_SERVER_ERROR"
+	internalErrorDetail   = "Error occurred please try after some time, if persist contact technical support"
+	correlationIdParamKey = "x-correlation-id"
+)
+
+// Problem is the RFC 7807 problem details body of the error responses, extended with the error code
+// and data of *utils.Error and the correlation id of the invocation.
+type Problem struct {
+	Type          string      `json:"type"`
+	Title         string      `json:"title"`
+	Status        int         `json:"status"`
+	Detail        string      `json:"detail,omitempty"`
+	Instance      string      `json:"instance,omitempty"`
+	ErrorCode     string      `json:"errorCode,omitempty"`
+	ErrorData     interface{} `json:"errorData,omitempty"`
+	CorrelationId string      `json:"correlationId,omitempty"`
+}
+
+// ErrorRenderer converts the error of an invocation into the response. Other triggers than the API
+// ones return the error to Lambda along with the response. The default one is RenderProblem.
+type ErrorRenderer func(invocation *Invocation, err error) events.APIGatewayProxyResponse
+
+// SetErrorRenderer replaces the error renderer, it can wrap RenderProblem or NewProblem to keep the
+// problem details and change the status or the body.
+//
+//	handler.SetErrorRenderer(func(invocation *eventprocessor.Invocation, err error) events.APIGatewayProxyResponse {
+//		res := eventprocessor.RenderProblem(invocation, err)
+//		res.Headers["Cache-Control"] = "no-store"
+//		return res
+//	})
+func (h *Handler) SetErrorRenderer(renderer ErrorRenderer) {
+	h.errorRenderer = renderer
+}
+
+// NewProblem builds the problem details of the error. *utils.Error keeps its status, message, code
+// and data, the details of panics are hidden and other errors are internal server errors.
+func NewProblem(invocation *Invocation, err error) *Problem {
+	problem := &Problem{Type: problemTypeBlank, Status: http.StatusInternalServerError, ErrorCode: internalErrorCode}
+	switch v := err.(type) {
+	case *utils.Error:
+		if v.StatusCode != 0 {
+			problem.Status = v.StatusCode
+		}
+		problem.Detail, problem.ErrorCode, problem.ErrorData = v.ErrorMessage, v.ErrorCode, v.ErrorData
+	case *PanicError:
+		problem.Detail = internalErrorDetail
+	default:
+		problem.Detail = err.Error()
+	}
+	problem.Title = http.StatusText(problem.Status)
+	if invocation != nil {
+		if invocation.APIRequest != nil {
+			problem.Instance = invocation.APIRequest.Path
+		}
+		if invocation.Log != nil {
+			problem.CorrelationId = invocation.Log.GetCorrelationParams()[correlationIdParamKey]
+		}
+	}
+	return problem
+}
+
+// RenderProblem renders the error as application/problem+json.
+func RenderProblem(invocation *Invocation, err error) events.APIGatewayProxyResponse {
+	problem := NewProblem(invocation, err)
+	blob, marshalErr := json.Marshal(problem)
+	if marshalErr != nil {
+		problem.ErrorData = nil
+		blob, _ = json.Marshal(problem)
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: problem.Status,
+		Headers:    map[string]string{"Content-Type": MediaTypeProblem},
+		Body:       string(blob),
+	}
+}
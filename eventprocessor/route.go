@@ -0,0 +1,66 @@
+package eventprocessor
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Empty is used as the Body, Query or Path type of a route that doesn't bind it.
+type Empty struct{}
+
+// Request is the typed request passed to a route handler. Body, QueryParams and PathParams are
+// allocated for every request and are never nil, Principal is nil for anonymous calls. Ctx is the
+// ctx of the invocation.
+type Request[Body, Query, Path any] struct {
+	Ctx         context.Context
+	Headers     map[string]string
+	Body        *Body
+	QueryParams *Query
+	PathParams  *Path
+	Event       *events.APIGatewayProxyRequest
+	Principal   *Principal
+}
+
+type TypedAPIHandler[Body, Query, Path, Resp any] func(request *Request[Body, Query, Path]) (int, Resp, error)
+
+// Route creates a type safe API for the handler. The Body, Query and Path types are bound from the
+// request body, query string and path parameters, use Empty for the ones which are not needed.
+//
+//	getAPI := eventprocessor.Route(m.GetCustomer)
+func Route[Body, Query, Path, Resp any](handler TypedAPIHandler[Body, Query, Path, Resp]) *API {
+	api := &API{
+		Body:         newRouteParam[Body](),
+		QueryParams:  newRouteParam[Query](),
+		PathParams:   newRouteParam[Path](),
+		responseType: reflect.TypeOf((*Resp)(nil)).Elem(),
+	}
+	api.invoke = func(call *apiCall) (int, interface{}, error) {
+		request := &Request[Body, Query, Path]{
+			Ctx:         call.ctx,
+			Headers:     call.headers,
+			Body:        typedParam[Body](call.body),
+			QueryParams: typedParam[Query](call.queryParams),
+			PathParams:  typedParam[Path](call.pathParams),
+			Event:       call.request,
+			Principal:   call.principal,
+		}
+		return handler(request)
+	}
+	return api
+}
+
+func newRouteParam[T any]() interface{} {
+	if reflect.TypeOf((*T)(nil)).Elem() == reflect.TypeOf(Empty{}) {
+		return nil
+	}
+	return new(T)
+}
+
+func typedParam[T any](value interface{}) *T {
+	if typed, ok := value.(*T); ok && typed != nil {
+		return typed
+	}
+	return new(T)
+}
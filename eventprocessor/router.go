@@ -0,0 +1,382 @@
+package eventprocessor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var pathParamPattern = regexp.MustCompile(`^\{([A-Za-z0-9_.\-]+)(\+?)\}$`)
+
+var routeMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// Router builds the route map returned by EventProcessor.GetAPIHandler. Groups share a resource
+// prefix and middlewares, and Build validates all the routes before the map is used.
+//
+//	r := eventprocessor.NewRouter()
+//	r.GET("/{customerId}", &eventprocessor.API{ApiHandler: m.Get, PathParams: &PathParams{}})
+//	pii := r.Group("/pii", authMiddleware)
+//	pii.POST("/upload", eventprocessor.Route(m.UploadPII))
+//	return r.MustBuild()
+type Router struct {
+	prefix     string
+	middleware []Middleware
+	routes     *[]*API
+}
+
+func NewRouter() *Router {
+	return &Router{routes: &[]*API{}}
+}
+
+// Use adds middlewares to the routes registered on the router after the call.
+func (r *Router) Use(middleware ...Middleware) {
+	r.middleware = append(r.middleware, middleware...)
+}
+
+// Group returns a router which registers its routes under the prefix with the middlewares of this
+// router followed by the given ones.
+func (r *Router) Group(prefix string, middleware ...Middleware) *Router {
+	groupMiddleware := make([]Middleware, 0, len(r.middleware)+len(middleware))
+	groupMiddleware = append(append(groupMiddleware, r.middleware...), middleware...)
+	return &Router{prefix: joinResource(r.prefix, prefix), middleware: groupMiddleware, routes: r.routes}
+}
+
+// Handle registers the handler for the method and resource. The handler can be an *API (see Route)
+// or an APIHandler or APIContextHandler function, the registered API is returned to allow further configuration.
+func (r *Router) Handle(method, resource string, handler interface{}, middleware ...Middleware) *API {
+	var api API
+	switch v := handler.(type) {
+	case *API:
+		if v != nil {
+			api = *v
+		}
+	case APIHandler:
+		api.ApiHandler = v
+	case func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error):
+		api.ApiHandler = v
+	case APIContextHandler:
+		api.ApiContextHandler = v
+	case func(ctx context.Context, headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error):
+		api.ApiContextHandler = v
+	default:
+		panic(fmt.Errorf("%v %v: unsupported handler type %T", method, resource, handler))
+	}
+	routeMiddleware := make([]Middleware, 0, len(r.middleware)+len(api.Middleware)+len(middleware))
+	routeMiddleware = append(append(append(routeMiddleware, r.middleware...), api.Middleware...), middleware...)
+	api.Method = strings.ToUpper(method)
+	api.Resource = joinResource(r.prefix, resource)
+	api.Middleware = routeMiddleware
+	*r.routes = append(*r.routes, &api)
+	return &api
+}
+
+func (r *Router) GET(resource string, handler interface{}, middleware ...Middleware) *API {
+	return r.Handle(http.MethodGet, resource, handler, middleware...)
+}
+
+func (r *Router) POST(resource string, handler interface{}, middleware ...Middleware) *API {
+	return r.Handle(http.MethodPost, resource, handler, middleware...)
+}
+
+func (r *Router) PUT(resource string, handler interface{}, middleware ...Middleware) *API {
+	return r.Handle(http.MethodPut, resource, handler, middleware...)
+}
+
+func (r *Router) PATCH(resource string, handler interface{}, middleware ...Middleware) *API {
+	return r.Handle(http.MethodPatch, resource, handler, middleware...)
+}
+
+func (r *Router) DELETE(resource string, handler interface{}, middleware ...Middleware) *API {
+	return r.Handle(http.MethodDelete, resource, handler, middleware...)
+}
+
+func (r *Router) OPTIONS(resource string, handler interface{}, middleware ...Middleware) *API {
+	return r.Handle(http.MethodOptions, resource, handler, middleware...)
+}
+
+// Build validates the registered routes and compiles them to the route map. Duplicate routes,
+// malformed resource templates, missing handlers and path / query / validation bindings which
+// can't be satisfied are all reported in the error.
+func (r *Router) Build() (map[string]map[string]*API, error) {
+	problems := make([]string, 0)
+	apiMap := make(map[string]map[string]*API)
+	for _, api := range *r.routes {
+		if _, ok := apiMap[api.Resource][api.Method]; ok {
+			problems = append(problems, fmt.Sprintf("%v %v: duplicate route", api.Method, api.Resource))
+			continue
+		}
+		if apiMap[api.Resource] == nil {
+			apiMap[api.Resource] = make(map[string]*API)
+		}
+		apiMap[api.Resource][api.Method] = api
+	}
+	if err := ValidateRoutes(apiMap); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid routes\n%v", strings.Join(problems, "\n"))
+	}
+	return apiMap, nil
+}
+
+// MustBuild is like Build but panics on invalid routes, it is meant to fail the cold start.
+func (r *Router) MustBuild() map[string]map[string]*API {
+	apiMap, err := r.Build()
+	if err != nil {
+		panic(err)
+	}
+	return apiMap
+}
+
+// ValidateRoutes checks a route map, routes with resource templates which only differ in the
+// parameter names are reported as duplicates as they can't be told apart on the raw path.
+func ValidateRoutes(apiMap map[string]map[string]*API) error {
+	problems := make([]string, 0)
+	shapes := make(map[string]string)
+	resources := make([]string, 0, len(apiMap))
+	for resource := range apiMap {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	for _, resource := range resources {
+		pathParams, err := parseResource(resource)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%v: %v", resource, err))
+			continue
+		}
+		methods := make([]string, 0, len(apiMap[resource]))
+		for method := range apiMap[resource] {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			api := apiMap[resource][method]
+			route := fmt.Sprintf("%v %v", method, resource)
+			if !routeMethods[method] {
+				problems = append(problems, fmt.Sprintf("%v: invalid method", route))
+			}
+			shape := method + " " + resourceShape(resource)
+			if other, ok := shapes[shape]; ok {
+				problems = append(problems, fmt.Sprintf("%v: conflicts with %v", route, other))
+			}
+			shapes[shape] = route
+			for _, problem := range validateAPIBindings(api, pathParams) {
+				problems = append(problems, fmt.Sprintf("%v: %v", route, problem))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%v", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+func joinResource(prefix, resource string) string {
+	resource = strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(resource, "/")
+	if len(resource) > 1 {
+		resource = strings.TrimSuffix(resource, "/")
+	}
+	return resource
+}
+
+// parseResource validates the resource template and returns its path parameter names.
+func parseResource(resource string) ([]string, error) {
+	if !strings.HasPrefix(resource, "/") {
+		return nil, fmt.Errorf("resource must start with /")
+	}
+	pathParams := make([]string, 0)
+	segments := splitPath(resource)
+	for i, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("empty path segment")
+		}
+		if !strings.ContainsAny(segment, "{}") {
+			continue
+		}
+		match := pathParamPattern.FindStringSubmatch(segment)
+		if match == nil {
+			return nil, fmt.Errorf("malformed path parameter %v", segment)
+		}
+		if match[2] == "+" && i != len(segments)-1 {
+			return nil, fmt.Errorf("greedy path parameter %v must be the last segment", segment)
+		}
+		for _, name := range pathParams {
+			if name == match[1] {
+				return nil, fmt.Errorf("duplicate path parameter %v", segment)
+			}
+		}
+		pathParams = append(pathParams, match[1])
+	}
+	return pathParams, nil
+}
+
+func resourceShape(resource string) string {
+	segments := splitPath(resource)
+	for i, segment := range segments {
+		if match := pathParamPattern.FindStringSubmatch(segment); match != nil {
+			segments[i] = "{" + match[2] + "}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+func validateAPIBindings(api *API, pathParams []string) []string {
+	problems := make([]string, 0)
+	if api == nil || (api.ApiHandler == nil && api.ApiContextHandler == nil && api.invoke == nil) {
+		return append(problems, "handler is missing")
+	}
+	if api.PathParams != nil {
+		fields, err := bindingFields(api.PathParams, fieldName)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("path params %v", err))
+		}
+		for _, name := range pathParams {
+			if _, ok := fields[strings.ToLower(name)]; !ok {
+				problems = append(problems, fmt.Sprintf("path parameter %v is not bound by %T", name, api.PathParams))
+			}
+		}
+		for key, field := range fields {
+			found := false
+			for _, name := range pathParams {
+				found = found || strings.ToLower(name) == key
+			}
+			if !found {
+				problems = append(problems, fmt.Sprintf("field %v of %T is not a path parameter", field.Name, api.PathParams))
+			} else if !isPathParamType(field.Type) {
+				problems = append(problems, fmt.Sprintf("field %v of %T has unsupported path param type %v", field.Name, api.PathParams, field.Type))
+			}
+		}
+	}
+	if api.QueryParams != nil {
+		fields, err := bindingFields(api.QueryParams, queryParamName)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("query params %v", err))
+		}
+		problems = append(problems, validateParamFields(api.QueryParams, fields, "query param")...)
+	}
+	// a MultipartForm body is bound as a whole, the form values are only bound to the other bodies
+	if api.Body != nil && isMultipartBody(reflect.TypeOf(api.Body)) && reflect.TypeOf(api.Body) != reflect.PtrTo(multipartFormType) {
+		if fields, err := bindingFields(api.Body, queryParamName); err == nil {
+			problems = append(problems, validateParamFields(api.Body, fields, "form value")...)
+		}
+	}
+	for _, registered := range []interface{}{api.Body, api.QueryParams, api.PathParams} {
+		if registered == nil {
+			continue
+		}
+		for _, problem := range checkValidateTags(reflect.TypeOf(registered), map[reflect.Type]bool{}) {
+			problems = append(problems, fmt.Sprintf("%T %v", registered, problem))
+		}
+	}
+	return problems
+}
+
+func bindingFields(registered interface{}, nameFunc func(reflect.StructField) string) (map[string]reflect.StructField, error) {
+	registeredType := reflect.TypeOf(registered)
+	if registeredType.Kind() == reflect.Ptr {
+		registeredType = registeredType.Elem()
+	}
+	fields := make(map[string]reflect.StructField)
+	if registeredType.Kind() != reflect.Struct {
+		return fields, fmt.Errorf("%T must be a pointer to struct", registered)
+	}
+	for i := 0; i < registeredType.NumField(); i++ {
+		field := registeredType.Field(i)
+		if name := nameFunc(field); field.IsExported() && name != "-" {
+			fields[strings.ToLower(name)] = field
+		}
+	}
+	return fields, nil
+}
+
+// validateParamFields checks the types of the fields bound from string values and their default tags,
+// file parts of multipart bodies are skipped.
+func validateParamFields(registered interface{}, fields map[string]reflect.StructField, kind string) []string {
+	problems := make([]string, 0)
+	for _, field := range fields {
+		if field.Type == filePartType || field.Type == filePartsType {
+			continue
+		}
+		if !isSupportedParamType(field.Type) {
+			problems = append(problems, fmt.Sprintf("field %v of %T has unsupported %v type %v", field.Name, registered, kind, field.Type))
+			continue
+		}
+		if defaultValue, ok := field.Tag.Lookup("default"); ok {
+			if err := setParamValue(reflect.New(field.Type).Elem(), defaultParamValues(field.Type, defaultValue)); err != nil {
+				problems = append(problems, fmt.Sprintf("field %v of %T has an invalid default %q, %v", field.Name, registered, defaultValue, err))
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// isSupportedParamType reports whether setParamValue can bind the type, slices of the scalar types and
+// pointers to them are supported.
+func isSupportedParamType(fieldType reflect.Type) bool {
+	if fieldType.Kind() == reflect.Slice && !isScalarParamType(fieldType) {
+		fieldType = fieldType.Elem()
+	}
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	if isScalarParamType(fieldType) || fieldType == durationType {
+		return true
+	}
+	switch fieldType.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// isPathParamType reports whether bindPathParams can bind the type, a path parameter is a single value.
+func isPathParamType(fieldType reflect.Type) bool {
+	if fieldType.Kind() == reflect.Slice && !isScalarParamType(fieldType) {
+		return false
+	}
+	return isSupportedParamType(fieldType)
+}
+
+// checkValidateTags parses the validate tags of the type so that invalid rules fail at start up.
+func checkValidateTags(valueType reflect.Type, visited map[reflect.Type]bool) (problems []string) {
+	for valueType.Kind() == reflect.Ptr || valueType.Kind() == reflect.Slice || valueType.Kind() == reflect.Array {
+		valueType = valueType.Elem()
+	}
+	if valueType.Kind() != reflect.Struct || visited[valueType] {
+		return
+	}
+	visited[valueType] = true
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if tag, ok := field.Tag.Lookup("validate"); ok {
+			if err := checkValidateTag(tag); err != nil {
+				problems = append(problems, fmt.Sprintf("field %v %v", field.Name, err))
+			}
+		}
+		problems = append(problems, checkValidateTags(field.Type, visited)...)
+	}
+	return
+}
+
+func checkValidateTag(tag string) error {
+	_, err := compileRules(tag)
+	return err
+}
@@ -3,86 +3,90 @@ package eventprocessor
 import (
 	"context"
 	"fmt"
-	"net/http"
-	"runtime/debug"
+	"net/url"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
-	"gobase-lambda/errornotification"
 	"gobase-lambda/utils"
 )
 
 type S3TriggerHandler func(payload interface{}) error
 
+// S3TriggerContextHandler is a S3TriggerHandler receiving the ctx of the invocation.
+type S3TriggerContextHandler func(ctx context.Context, payload interface{}) error
+
+// S3Trigger maps an object key prefix to its handler, S3TriggerContextHandler is used instead of
+// S3TriggerHandler when it is set.
 type S3Trigger struct {
-	KeyPrefix        string
-	S3TriggerHandler S3TriggerHandler
+	KeyPrefix               string
+	S3TriggerHandler        S3TriggerHandler
+	S3TriggerContextHandler S3TriggerContextHandler
+}
+
+func (h *Handler) HandleS3TriggerRequest(ctx context.Context, request events.S3Event) (events.APIGatewayProxyResponse, error) {
+	invocation := h.newInvocation(ctx, EventS3, &request)
+	result, err := h.invoke(invocation, func(invocation *Invocation) (interface{}, error) {
+		eventProcessor := h.eventProcessorFunc(invocation.Ctx, invocation.Log, invocation.Event, EventS3)
+		s3TriggerMap := eventProcessor.GetS3EventHandler()
+		recordErrors, errs := make([]*RecordError, 0), make([]error, 0)
+		for i := range request.Records {
+			record := &request.Records[i]
+			recordErr := h.processS3Record(invocation.Ctx, s3TriggerMap, record)
+			if recordErr != nil {
+				recordErrors = append(recordErrors, newRecordError(record.S3.Object.Key, recordErr))
+				errs = append(errs, recordErr)
+			}
+		}
+		err := newRecordsError(len(request.Records), recordErrors, errs)
+		if err != nil {
+			return nil, err
+		}
+		return events.APIGatewayProxyResponse{}, nil
+	})
+	return triggerResponse(invocation, result, err)
 }
 
-func (h *Handler) HandleS3TriggerRequest(ctx context.Context, request events.S3Event) (res events.APIGatewayProxyResponse, err error) {
+func (h *Handler) processS3Record(ctx context.Context, s3TriggerMap map[string]map[string]map[string]*S3Trigger, record *events.S3EventRecord) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			h.log.Error("Full Request", request)
-			h.log.Error("Panic Stack", string(debug.Stack()))
-			h.log.Error("Panic Recovery", r)
-			switch v := r.(type) {
-			case *utils.Error:
-				res.StatusCode = v.StatusCode
-				res.Body = fmt.Sprintf(`{"error":%v}`, v)
-			default:
-				res.StatusCode = http.StatusInternalServerError
-				res.Body = `{"error": "Error occurred please try after some time, if persist contact technical support"}`
-				notification := errornotification.ErrorNotifier{
-					StatusCode:   "500",
-					StackTrace:   string(debug.Stack()),
-					ErrorMessage: fmt.Sprintf("%s", r),
-					Log:          *h.log,
-				}
-				notification.PublishToSqs()
-				notification.PublishToSns()
-			}
-		} else {
-			if err != nil {
-				h.log.Error("SQS Event Error", err)
-				custErr, ok := err.(*utils.Error)
-				if ok {
-					res.StatusCode = custErr.StatusCode
-				} else {
-					res.StatusCode = http.StatusInternalServerError
-				}
-				res.Body = fmt.Sprintf(`{"error":%v}`, err)
-			}
+			err = h.recordPanicError(r, record, true)
+		} else if err != nil {
+			h.log.Error("S3 Record", record)
+			h.log.Error("S3 Record Error", err)
 		}
-		h.log.Info("Full Response", res)
 	}()
-	h.setCorrelationParams(map[string]string{})
-	h.log.Debug("Full Request", request)
-	eventProcessor := h.eventProcessorFunc(ctx, h.log, &request, EventS3)
-	s3TriggerMap := eventProcessor.GetS3EventHandler()
-	bucket := request.Records[0].S3.Bucket.Name
-	objectKey := request.Records[0].S3.Object.Key
-	eventName := request.Records[0].EventName
+	if record.S3.Object.URLDecodedKey == "" {
+		objectKey, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			panic(utils.NewHTTPBadRequestError(fmt.Sprintf("invalid object key : %v", err), record.S3.Object.Key))
+		}
+		record.S3.Object.URLDecodedKey = objectKey
+	}
+	bucket := record.S3.Bucket.Name
+	objectKey := record.S3.Object.URLDecodedKey
+	eventName := record.EventName
 	newHandler := extractS3TriggerHandler(s3TriggerMap, eventName, objectKey, bucket)
-	err = newHandler.S3TriggerHandler(&request)
-
-	return
+	payload := &events.S3Event{Records: []events.S3EventRecord{*record}}
+	if newHandler.S3TriggerContextHandler != nil {
+		return newHandler.S3TriggerContextHandler(ctx, payload)
+	}
+	return newHandler.S3TriggerHandler(payload)
 }
 
 func extractS3TriggerHandler(s3TriggerMap map[string]map[string]map[string]*S3Trigger, eventName, objectKey, bucket string) *S3Trigger {
-	if s3TriggerMap[bucket] != nil {
-		if s3TriggerMap[bucket][eventName] != nil {
-			keyMap := s3TriggerMap[bucket][eventName]
-			keyPrefixes := make([]string, 0, len(keyMap))
-			for k := range keyMap {
-				keyPrefixes = append(keyPrefixes, k)
-			}
-			for _, a := range keyPrefixes {
-				if strings.Contains(objectKey, a) {
-					triggerObj := s3TriggerMap[bucket][eventName][a]
-					return triggerObj
-				}
-			}
+	var triggerObj *S3Trigger
+	matchedPrefix := ""
+	for keyPrefix, trigger := range s3TriggerMap[bucket][eventName] {
+		if strings.Contains(objectKey, keyPrefix) && (triggerObj == nil || len(keyPrefix) > len(matchedPrefix)) {
+			triggerObj, matchedPrefix = trigger, keyPrefix
 		}
 	}
-	panic(utils.NewHTTPBadRequestError("Unknown S3 Path of triggered event name configured", s3TriggerMap))
+	if triggerObj == nil {
+		panic(utils.NewHTTPBadRequestError("Unknown S3 Path of triggered event name configured", map[string]string{
+			"bucket":    bucket,
+			"eventName": eventName,
+			"objectKey": objectKey,
+		}))
+	}
+	return triggerObj
 }
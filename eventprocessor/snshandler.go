@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"runtime/debug"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -15,73 +14,73 @@ import (
 
 type SNSHandler func(payload interface{}) error
 
+// SNSContextHandler is a SNSHandler receiving the ctx of the invocation.
+type SNSContextHandler func(ctx context.Context, payload interface{}) error
+
+// SNS maps a topic event to its handler, SnsContextHandler is used instead of SnsHandler when it is set.
 type SNS struct {
-	Topic      string
-	Event      string
-	SnsHandler SNSHandler
+	Topic             string
+	Event             string
+	SnsHandler        SNSHandler
+	SnsContextHandler SNSContextHandler
+}
+
+func (h *Handler) HandleSNSRequest(ctx context.Context, request events.SNSEvent) (events.APIGatewayProxyResponse, error) {
+	invocation := h.newInvocation(ctx, EventSNS, &request)
+	result, err := h.invoke(invocation, func(invocation *Invocation) (interface{}, error) {
+		eventProcessor := h.eventProcessorFunc(invocation.Ctx, invocation.Log, invocation.Event, EventSNS)
+		snsMap := eventProcessor.GetSNSHandler()
+		recordErrors, errs := make([]*RecordError, 0), make([]error, 0)
+		for i := range request.Records {
+			record := &request.Records[i]
+			recordErr := h.processSNSRecord(invocation.Ctx, snsMap, record)
+			if recordErr != nil {
+				recordErrors = append(recordErrors, newRecordError(record.SNS.MessageID, recordErr))
+				errs = append(errs, recordErr)
+			}
+		}
+		err := newRecordsError(len(request.Records), recordErrors, errs)
+		if err != nil {
+			return nil, err
+		}
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent}, nil
+	})
+	return triggerResponse(invocation, result, err)
 }
 
-func (h *Handler) HandleSNSRequest(ctx context.Context, request events.SNSEvent) (res events.APIGatewayProxyResponse, err error) {
+func (h *Handler) processSNSRecord(ctx context.Context, snsMap map[string]map[string]*SNS, record *events.SNSEventRecord) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			h.log.Error("Full Request", request)
-			h.log.Error("Panic Stack", string(debug.Stack()))
-			h.log.Error("Panic Recovery", r)
-			switch v := r.(type) {
-			case *utils.Error:
-				res.StatusCode = v.StatusCode
-				res.Body = fmt.Sprintf(`{"error":%v}`, v)
-			default:
-				res.StatusCode = http.StatusInternalServerError
-				res.Body = `{"error": "Error occurred please try after some time, if persist contact technical support"}`
-			}
-		} else {
-			if err != nil {
-				h.log.Error("SNS Error", err)
-				custErr, ok := err.(*utils.Error)
-				if ok {
-					res.StatusCode = custErr.StatusCode
-				} else {
-					res.StatusCode = http.StatusInternalServerError
-				}
-				res.Body = fmt.Sprintf(`{"error":%v}`, err)
-			}
+			err = h.recordPanicError(r, record, false)
+		} else if err != nil {
+			h.log.Error("SNS Record", record)
+			h.log.Error("SNS Record Error", err)
 		}
-		h.log.Info("Full Response", res)
 	}()
-	h.setCorrelationParams(map[string]string{})
-	h.log.Debug("Full Request", request)
-	eventProcessor := h.eventProcessorFunc(ctx, h.log, &request, EventSNS)
-	snsMap := eventProcessor.GetSNSHandler()
-	event, topic, payload := extractSNSRequest(&request)
+	event, topic, payload := extractSNSRequest(record)
 	topicMap, topicExists := snsMap[topic]
-	if topicExists {
-		handler, eventExists := topicMap[event]
-		if eventExists {
-			err = handler.SnsHandler(payload)
-			if err != nil {
-				panic(err)
-			}
-			res.StatusCode = http.StatusNoContent
-			res.Body = ""
-		} else {
-			errorMessage := fmt.Sprintf("event %v %v is not mapped", topic, event)
-			h.log.Alert(errorMessage, snsMap)
-			panic(utils.NewHTTPNotFoundError(errorMessage, nil))
-		}
-	} else {
+	if !topicExists {
 		errorMessage := fmt.Sprintf("topic %v not mapped", topic)
 		h.log.Alert(errorMessage, snsMap)
 		panic(utils.NewHTTPNotFoundError(errorMessage, nil))
 	}
-	return
+	handler, eventExists := topicMap[event]
+	if !eventExists {
+		errorMessage := fmt.Sprintf("event %v %v is not mapped", topic, event)
+		h.log.Alert(errorMessage, snsMap)
+		panic(utils.NewHTTPNotFoundError(errorMessage, nil))
+	}
+	if handler.SnsContextHandler != nil {
+		return handler.SnsContextHandler(ctx, payload)
+	}
+	return handler.SnsHandler(payload)
 }
 
-func extractSNSRequest(request *events.SNSEvent) (event, topic string, payload map[string]interface{}) {
+func extractSNSRequest(record *events.SNSEventRecord) (event, topic string, payload map[string]interface{}) {
 	logger := log.GetDefaultLogger()
-	topicArnSplit := strings.Split(request.Records[0].SNS.TopicArn, ":")
+	topicArnSplit := strings.Split(record.SNS.TopicArn, ":")
 	topic = topicArnSplit[len(topicArnSplit)-1]
-	unmarshallError := json.Unmarshal([]byte(request.Records[0].SNS.Message), &payload)
+	unmarshallError := json.Unmarshal([]byte(record.SNS.Message), &payload)
 	if unmarshallError != nil {
 		logger.Error("payload unmarshal failed", unmarshallError.Error())
 		return
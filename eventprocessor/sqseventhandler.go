@@ -3,76 +3,122 @@ package eventprocessor
 import (
 	"context"
 	"fmt"
-	"net/http"
-	"runtime/debug"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
-	"gobase-lambda/errornotification"
 	"gobase-lambda/utils"
 )
 
 type SQSHandler func(payload interface{}) error
 
+type SQSRecordHandler func(record *events.SQSMessage) error
+
+// SQSContextHandler and SQSRecordContextHandler receive the ctx of the invocation, they are used
+// instead of SQSHandler and SQSRecordHandler when they are set.
+type SQSContextHandler func(ctx context.Context, payload interface{}) error
+
+type SQSRecordContextHandler func(ctx context.Context, record *events.SQSMessage) error
+
 type SQS struct {
-	SQSHandler SQSHandler
+	SQSHandler              SQSHandler
+	SQSRecordHandler        SQSRecordHandler
+	SQSContextHandler       SQSContextHandler
+	SQSRecordContextHandler SQSRecordContextHandler
+}
+
+func (h *Handler) HandleSQSRequest(ctx context.Context, request events.SQSEvent) (events.APIGatewayProxyResponse, error) {
+	invocation := h.newInvocation(ctx, EventSQS, &request)
+	result, err := h.invoke(invocation, func(invocation *Invocation) (interface{}, error) {
+		eventProcessor := h.eventProcessorFunc(invocation.Ctx, invocation.Log, invocation.Event, EventSQS)
+		sqsMap := eventProcessor.GetSQSEventHandler()
+		queueArn := request.Records[0].EventSourceARN
+		newHandler := extractQueueHandler(sqsMap, queueArn)
+		return events.APIGatewayProxyResponse{}, newHandler.handle(invocation.Ctx, &request)
+	})
+	return triggerResponse(invocation, result, err)
+}
+
+// HandleSQSBatchRequest processes every record of the event on its own and reports only the failed
+// messages through BatchItemFailures, so the event source mapping has to be configured with
+// ReportBatchItemFailures. Records are passed to SQSRecordHandler when it is set, otherwise to
+// SQSHandler as a single record SQSEvent. A failure outside the records fails the whole batch.
+func (h *Handler) HandleSQSBatchRequest(ctx context.Context, request events.SQSEvent) (events.SQSEventResponse, error) {
+	invocation := h.newInvocation(ctx, EventSQS, &request)
+	result, err := h.invoke(invocation, func(invocation *Invocation) (interface{}, error) {
+		eventProcessor := h.eventProcessorFunc(invocation.Ctx, invocation.Log, invocation.Event, EventSQS)
+		sqsMap := eventProcessor.GetSQSEventHandler()
+		res := events.SQSEventResponse{BatchItemFailures: make([]events.SQSBatchItemFailure, 0)}
+		for i := range request.Records {
+			record := &request.Records[i]
+			recordErr := h.processSQSRecord(invocation.Ctx, sqsMap, record)
+			if recordErr != nil {
+				res.BatchItemFailures = append(res.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: record.MessageId})
+			}
+		}
+		return res, nil
+	})
+	if res, ok := result.(events.SQSEventResponse); ok && err == nil {
+		return res, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("%v", proxyResponse(invocation, result, nil).Body)
+	}
+	return events.SQSEventResponse{}, err
 }
 
-func (h *Handler) HandleSQSRequest(ctx context.Context, request events.SQSEvent) (res events.APIGatewayProxyResponse, err error) {
+func (h *Handler) processSQSRecord(ctx context.Context, sqsMap map[string]*SQS, record *events.SQSMessage) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			h.log.Error("Full Request", request)
-			h.log.Error("Panic Stack", string(debug.Stack()))
-			h.log.Error("Panic Recovery", r)
-			switch v := r.(type) {
-			case *utils.Error:
-				res.StatusCode = v.StatusCode
-				res.Body = fmt.Sprintf(`{"error":%v}`, v)
-			default:
-				res.StatusCode = http.StatusInternalServerError
-				res.Body = `{"error": "Error occurred please try after some time, if persist contact technical support"}`
-				notification := errornotification.ErrorNotifier{
-					StatusCode:   "500",
-					StackTrace:   string(debug.Stack()),
-					ErrorMessage: fmt.Sprintf("%s", r),
-					Log:          *h.log,
-				}
-				notification.PublishToSqs()
-				notification.PublishToSns()
-			}
-		} else {
-			if err != nil {
-				h.log.Error("SQS Event Error", err)
-				custErr, ok := err.(*utils.Error)
-				if ok {
-					res.StatusCode = custErr.StatusCode
-				} else {
-					res.StatusCode = http.StatusInternalServerError
-				}
-				res.Body = fmt.Sprintf(`{"error":%v}`, err)
-			}
+			err = h.recordPanicError(r, record, true)
+		} else if err != nil {
+			h.log.Error("SQS Record", record)
+			h.log.Error("SQS Record Error", err)
 		}
-		h.log.Info("Full Response", res)
 	}()
-	h.setCorrelationParams(map[string]string{})
-	h.log.Debug("Full Request", request)
-	eventProcessor := h.eventProcessorFunc(ctx, h.log, &request, EventSQS)
-	sqsMap := eventProcessor.GetSQSEventHandler()
-	queueArn := request.Records[0].EventSourceARN
-	newHandler := extractQueueHandler(sqsMap, queueArn)
-	err = newHandler.SQSHandler(&request)
-	return
+	newHandler := extractQueueHandler(sqsMap, record.EventSourceARN)
+	switch {
+	case newHandler.SQSRecordContextHandler != nil:
+		return newHandler.SQSRecordContextHandler(ctx, record)
+	case newHandler.SQSRecordHandler != nil:
+		return newHandler.SQSRecordHandler(record)
+	}
+	return newHandler.handle(ctx, &events.SQSEvent{Records: []events.SQSMessage{*record}})
+}
+
+func (s *SQS) handle(ctx context.Context, request *events.SQSEvent) error {
+	if s.SQSContextHandler != nil {
+		return s.SQSContextHandler(ctx, request)
+	}
+	return s.SQSHandler(request)
+}
+
+// isSQSRecordMode reports whether the queue of the event is registered with a record handler.
+func (h *Handler) isSQSRecordMode(ctx context.Context, request *events.SQSEvent) bool {
+	if len(request.Records) == 0 {
+		return false
+	}
+	eventProcessor := h.eventProcessorFunc(ctx, h.log, request, EventSQS)
+	queueHandler := findQueueHandler(eventProcessor.GetSQSEventHandler(), request.Records[0].EventSourceARN)
+	return queueHandler != nil && (queueHandler.SQSRecordHandler != nil || queueHandler.SQSRecordContextHandler != nil)
 }
 
 func extractQueueHandler(sqsMap map[string]*SQS, queueArn string) *SQS {
+	queueHandler := findQueueHandler(sqsMap, queueArn)
+	if queueHandler == nil {
+		panic(utils.NewHTTPBadRequestError("Unknown Queue name configured", queueArn))
+	}
+	return queueHandler
+}
+
+func findQueueHandler(sqsMap map[string]*SQS, queueArn string) *SQS {
 	stage := utils.GetenvMust("stage")
+	queueSlice := strings.Split(queueArn, ":")
+	queueName := queueSlice[len(queueSlice)-1]
 	for key, value := range sqsMap {
-		queueSlice := strings.Split(queueArn, ":")
-		queueName := queueSlice[len(queueSlice)-1]
 		key = fmt.Sprintf(`%s_%s`, stage, key)
 		if strings.EqualFold(queueName, key) {
 			return value
 		}
 	}
-	panic(utils.NewHTTPBadRequestError("Unknown Queue name configured", sqsMap))
+	return nil
 }
@@ -0,0 +1,314 @@
+package eventprocessor
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gobase-lambda/utils"
+)
+
+// FieldError is a single validation failure, Field is the json path of the field and In is the part
+// of the request it was bound from (body, query or path).
+type FieldError struct {
+	Field  string `json:"field"`
+	In     string `json:"in"`
+	Reason string `json:"reason"`
+}
+
+const (
+	paramInBody  = "body"
+	paramInQuery = "query"
+	paramInPath  = "path"
+)
+
+// validationRule is a parsed rule of a validate tag, number is the bound of min and max and the length
+// of len.
+type validationRule struct {
+	name   string
+	param  string
+	number float64
+	regex  *regexp.Regexp
+}
+
+var rulesCache sync.Map
+
+// Validate checks the struct against its validate tags and returns the failing fields. The rules are
+// comma separated, regex has to be the last rule as the expression may contain commas.
+//
+//	Name   string   `json:"name" validate:"required,min=3,max=50"`
+//	PAN    string   `json:"pan" validate:"len=10,regex=^[A-Z]{5}[0-9]{4}[A-Z]$"`
+//	Gender string   `json:"gender" validate:"enum=M|F|O"`
+//	Email  *string  `json:"email" validate:"email"`
+//	Tags   []string `json:"tags" validate:"max=5"`
+//
+// Empty strings and nil pointers are only checked by required. Nested structs, pointers to structs
+// and slices of structs are validated as well. The tags are parsed once, an invalid tag panics, Build
+// and ValidateRoutes report them for the route types.
+func Validate(value interface{}, in string) []*FieldError {
+	fieldErrors := make([]*FieldError, 0)
+	if value == nil {
+		return fieldErrors
+	}
+	validateValue(reflect.ValueOf(value), "", in, &fieldErrors)
+	return fieldErrors
+}
+
+func validateValue(value reflect.Value, path, in string, fieldErrors *[]*FieldError) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		valueType := value.Type()
+		for i := 0; i < valueType.NumField(); i++ {
+			field := valueType.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fieldPath := fieldName(field)
+			if fieldPath == "-" {
+				continue
+			}
+			if path != "" {
+				fieldPath = path + "." + fieldPath
+			}
+			fieldValue := value.Field(i)
+			if tag, ok := field.Tag.Lookup("validate"); ok {
+				if reason := validateField(fieldValue, tag); reason != "" {
+					*fieldErrors = append(*fieldErrors, &FieldError{Field: fieldPath, In: in, Reason: reason})
+					continue
+				}
+			}
+			validateValue(fieldValue, fieldPath, in, fieldErrors)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			validateValue(value.Index(i), fmt.Sprintf("%s[%d]", path, i), in, fieldErrors)
+		}
+	}
+}
+
+// fieldName returns the name of the field as seen by the client, from the json or structs tag.
+func fieldName(field reflect.StructField) string {
+	for _, tagName := range []string{"json", "structs"} {
+		if tag, ok := field.Tag.Lookup(tagName); ok {
+			name := strings.Split(tag, ",")[0]
+			if name != "" {
+				return name
+			}
+		}
+	}
+	return field.Name
+}
+
+func validateField(value reflect.Value, tag string) string {
+	rules, err := compileRules(tag)
+	if err != nil {
+		panic(err)
+	}
+	isNil := (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) && value.IsNil()
+	for _, rule := range rules {
+		if rule.name == "required" {
+			if isNil || value.IsZero() || (hasLength(value) && value.Len() == 0) {
+				return "is required"
+			}
+		}
+	}
+	if isNil {
+		return ""
+	}
+	for value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.String && value.Len() == 0 {
+		return ""
+	}
+	for _, rule := range rules {
+		var reason string
+		switch rule.name {
+		case "min":
+			reason = validateBound(value, rule, true)
+		case "max":
+			reason = validateBound(value, rule, false)
+		case "len":
+			reason = validateLength(value, int(rule.number))
+		case "enum":
+			reason = validateEnum(value, rule.param)
+		case "email":
+			reason = validateEmail(value)
+		case "regex":
+			reason = validateRegex(value, rule)
+		}
+		if reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// compileRules parses the validate tag once, the bounds, lengths and regexes are checked so that an
+// invalid tag fails when the routes are validated instead of on the first request.
+func compileRules(tag string) ([]validationRule, error) {
+	if cached, ok := rulesCache.Load(tag); ok {
+		return cached.([]validationRule), nil
+	}
+	rules := make([]validationRule, 0)
+	for _, rule := range splitRules(tag) {
+		name, param, _ := strings.Cut(rule, "=")
+		compiled := validationRule{name: name, param: param}
+		switch name {
+		case "required", "email", "enum":
+		case "min", "max":
+			bound, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid validation bound %v", param)
+			}
+			compiled.number = bound
+		case "len":
+			length, err := strconv.Atoi(param)
+			if err != nil {
+				return nil, fmt.Errorf("invalid validation length %v", param)
+			}
+			compiled.number = float64(length)
+		case "regex":
+			expression, err := regexp.Compile(param)
+			if err != nil {
+				return nil, fmt.Errorf("invalid validation regex %v : %v", param, err)
+			}
+			compiled.regex = expression
+		default:
+			return nil, fmt.Errorf("unknown validation rule %v", rule)
+		}
+		rules = append(rules, compiled)
+	}
+	rulesCache.Store(tag, rules)
+	return rules, nil
+}
+
+func splitRules(tag string) []string {
+	if index := strings.Index(tag, "regex="); index >= 0 {
+		rules := splitRules(strings.TrimSuffix(tag[:index], ","))
+		return append(rules, tag[index:])
+	}
+	rules := make([]string, 0)
+	for _, rule := range strings.Split(tag, ",") {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func hasLength(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return true
+	}
+	return false
+}
+
+func validateBound(value reflect.Value, rule validationRule, isMin bool) string {
+	bound, param := rule.number, rule.param
+	var actual float64
+	isLength := hasLength(value)
+	switch {
+	case isLength:
+		actual = float64(value.Len())
+	case value.CanInt():
+		actual = float64(value.Int())
+	case value.CanUint():
+		actual = float64(value.Uint())
+	case value.CanFloat():
+		actual = value.Float()
+	default:
+		return ""
+	}
+	if isMin && actual < bound {
+		if isLength {
+			return fmt.Sprintf("length must be at least %v", param)
+		}
+		return fmt.Sprintf("must be at least %v", param)
+	}
+	if !isMin && actual > bound {
+		if isLength {
+			return fmt.Sprintf("length must be at most %v", param)
+		}
+		return fmt.Sprintf("must be at most %v", param)
+	}
+	return ""
+}
+
+func validateLength(value reflect.Value, length int) string {
+	if hasLength(value) && value.Len() != length {
+		return fmt.Sprintf("length must be %v", length)
+	}
+	return ""
+}
+
+func validateEnum(value reflect.Value, param string) string {
+	allowed := strings.Split(param, "|")
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		for i := 0; i < value.Len(); i++ {
+			if reason := validateEnum(value.Index(i), param); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	}
+	actual := fmt.Sprintf("%v", value.Interface())
+	for _, v := range allowed {
+		if actual == v {
+			return ""
+		}
+	}
+	return fmt.Sprintf("must be one of %v", strings.Join(allowed, ", "))
+}
+
+func validateEmail(value reflect.Value) string {
+	if value.Kind() != reflect.String || value.String() == "" {
+		return ""
+	}
+	address, err := mail.ParseAddress(value.String())
+	if err != nil || address.Address != value.String() {
+		return "must be a valid email address"
+	}
+	return ""
+}
+
+func validateRegex(value reflect.Value, rule validationRule) string {
+	if value.Kind() != reflect.String || value.String() == "" {
+		return ""
+	}
+	if !rule.regex.MatchString(value.String()) {
+		return fmt.Sprintf("must match %v", rule.param)
+	}
+	return ""
+}
+
+// validateAPIRequest validates the bound body, query and path params of the call and panics with a
+// 400 error listing all the failing fields.
+func validateAPIRequest(call *apiCall) {
+	fieldErrors := make([]*FieldError, 0)
+	params := []struct {
+		in    string
+		value interface{}
+	}{{paramInPath, call.pathParams}, {paramInQuery, call.queryParams}, {paramInBody, call.body}}
+	for _, param := range params {
+		if param.value == nil || reflect.TypeOf(param.value).Kind() != reflect.Ptr {
+			continue
+		}
+		fieldErrors = append(fieldErrors, Validate(param.value, param.in)...)
+	}
+	if len(fieldErrors) > 0 {
+		panic(utils.NewError(http.StatusBadRequest, "request validation failed", "VALIDATION_FAILED", fieldErrors))
+	}
+}
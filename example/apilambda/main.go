@@ -9,6 +9,9 @@ import (
 
 func main() {
 	handler := eventprocessor.GetHandler(true, example.NewManager)
+	handler.MustValidateRoutes()
+	handler.SetCompression(&eventprocessor.CompressionConfig{MinSize: 4 * 1024})
+	handler.ServeOpenAPI(eventprocessor.OpenAPIInfo{Title: "gobase example API", Version: "1.0.0"})
 	logger := log.GetDefaultLogger()
 	logger.Info("Lambda initiated", nil)
 	lambda.Start(handler.HandleAPIRequest)
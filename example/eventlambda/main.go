@@ -0,0 +1,16 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/lambda"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+	"gobase-lambda/log"
+)
+
+func main() {
+	handler := eventprocessor.GetHandler(true, example.NewManager)
+	handler.MustValidateRoutes()
+	logger := log.GetDefaultLogger()
+	logger.Info("Lambda initiated", nil)
+	lambda.Start(handler.HandleEvent)
+}
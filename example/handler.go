@@ -5,11 +5,15 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sync"
 
+	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-xray-sdk-go/xray"
 	"github.com/google/uuid"
 	"gobase-lambda/aws"
 	"gobase-lambda/eventprocessor"
+	"gobase-lambda/eventprocessor/idempotency"
 	"gobase-lambda/log"
 	"gobase-lambda/utils"
 	"gobase-lambda/utils/http"
@@ -22,77 +26,65 @@ type Manager struct {
 	eventType eventprocessor.EventType
 }
 
+// apiRoutes are built and validated once per process, the handlers are called on the Manager of the
+// invocation, see managerOf.
+var apiRoutes = sync.OnceValue(func() map[string]map[string]*eventprocessor.API {
+	r := eventprocessor.NewRouter()
+	r.GET("/{customerId}", &eventprocessor.API{
+		ApiContextHandler: apiHandler((*Manager).Get),
+		PathParams:        &PathParams{},
+		QueryParams:       &QueryParams{},
+	})
+	r.POST("/{customerId}", &eventprocessor.API{
+		ApiContextHandler: apiHandler((*Manager).Post),
+		Body:              &Payload{},
+		PathParams:        &PathParams{},
+		QueryParams:       &QueryParams{},
+	}, idempotent(aws.GetTableName("IDEMPOTENCY")))
+	r.POST("/upload", route((*Manager).Upload))
+	r.POST("/upload/file", route((*Manager).UploadFile)).Multipart = &eventprocessor.MultipartLimits{
+		MaxFileSize:  5 * 1024 * 1024,
+		AllowedTypes: []string{"application/pdf", "image/*"},
+	}
+	r.POST("/download", route((*Manager).Download))
+	r.POST("/download/file", route((*Manager).DownloadFile))
+	r.GET("/step-func", apiHandler((*Manager).StepFuncInvocation))
+
+	pii := r.Group("/pii")
+	pii.POST("/upload", route((*Manager).UploadPII))
+	pii.POST("/download", route((*Manager).DownloadPII))
+	return r.MustBuild()
+})
+
 func (m *Manager) GetAPIHandler() map[string]map[string]*eventprocessor.API {
-	resource := "/{customerId}"
-	getAPI := &eventprocessor.API{
-		Resource:    resource,
-		Method:      "GET",
-		ApiHandler:  m.Get,
-		PathParams:  &PathParams{},
-		QueryParams: &QueryParams{},
-	}
-	postAPI := &eventprocessor.API{
-		Resource:    resource,
-		Method:      "POST",
-		ApiHandler:  m.Post,
-		Body:        &Payload{},
-		PathParams:  &PathParams{},
-		QueryParams: &QueryParams{},
-	}
-	uploadDoc := &eventprocessor.API{
-		Resource:   "/upload",
-		Method:     "POST",
-		ApiHandler: m.Upload,
-		Body:       &Upload{},
-	}
-	uploadPiiDoc := &eventprocessor.API{
-		Resource:   "/pii/upload",
-		Method:     "POST",
-		ApiHandler: m.UploadPII,
-		Body:       &Upload{},
-	}
-	downloadDoc := &eventprocessor.API{
-		Resource:   "/download",
-		Method:     "POST",
-		ApiHandler: m.Download,
-		Body:       &Upload{},
-	}
-	downloadPiiDoc := &eventprocessor.API{
-		Resource:   "/pii/download",
-		Method:     "POST",
-		ApiHandler: m.DownloadPII,
-		Body:       &Upload{},
-	}
-	stepFuncInvocation := &eventprocessor.API{
-		Resource:    "/step-func",
-		Method:      "GET",
-		ApiHandler:  m.StepFuncInvocation,
-		PathParams:  nil,
-		QueryParams: nil,
-	}
-	return map[string]map[string]*eventprocessor.API{
-		resource: {
-			getAPI.Method:  getAPI,
-			postAPI.Method: postAPI,
-		},
-		uploadDoc.Resource: {
-			uploadDoc.Method: uploadDoc,
-		},
-		uploadPiiDoc.Resource: {
-			uploadPiiDoc.Method: uploadPiiDoc,
-		},
-		downloadDoc.Resource: {
-			downloadDoc.Method: downloadDoc,
-		},
-		downloadPiiDoc.Resource: {
-			downloadPiiDoc.Method: downloadPiiDoc,
-		},
-		"/step-func": {
-			stepFuncInvocation.Method: stepFuncInvocation,
-		},
+	return apiRoutes()
+}
+
+// managerOf returns the Manager of an API invocation from the ctx passed to the handlers.
+func managerOf(ctx context.Context) *Manager {
+	return &Manager{log: eventprocessor.GetLogger(ctx), ctx: ctx}
+}
+
+func apiHandler(handler func(m *Manager, headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error)) eventprocessor.APIContextHandler {
+	return func(ctx context.Context, headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+		return handler(managerOf(ctx), headers, pathParam, jsonBody, queryParams)
 	}
 }
 
+func route[Body, Query, Path, Resp any](handler func(m *Manager, request *eventprocessor.Request[Body, Query, Path]) (int, Resp, error)) *eventprocessor.API {
+	return eventprocessor.Route(func(request *eventprocessor.Request[Body, Query, Path]) (int, Resp, error) {
+		return handler(managerOf(request.Ctx), request)
+	})
+}
+
+// idempotent creates the idempotency store with the ctx of the invocation as the routes are shared.
+func idempotent(table string) eventprocessor.Middleware {
+	return eventprocessor.MiddlewareFunc(func(invocation *eventprocessor.Invocation, next eventprocessor.Next) (interface{}, error) {
+		store := idempotency.NewDynamoDbStore(invocation.Ctx, aws.GetDefaultDynamoDbClient(invocation.Ctx), table)
+		return idempotency.Middleware(idempotency.Config{Store: store}).Handle(invocation, next)
+	})
+}
+
 func (m *Manager) GetSNSHandler() map[string]map[string]*eventprocessor.SNS {
 	topic := "dev_MFCORE_PAYMENT"
 	event_rejected, event_confirmed := "transaction.rejected", "transaction.confirmed"
@@ -112,15 +104,56 @@ func (m *Manager) GetSNSHandler() map[string]map[string]*eventprocessor.SNS {
 	}
 }
 
+func (m *Manager) GetEventBridgeHandler() map[string]map[string][]*eventprocessor.EventBridge {
+	return map[string]map[string][]*eventprocessor.EventBridge{
+		"mfcore.payment": {
+			"Transaction Updated": {
+				{
+					Match:              map[string]interface{}{"$.status": "REJECTED"},
+					Detail:             &TransactionEvent{},
+					EventBridgeHandler: m.TransactionUpdateRejected,
+				},
+				{
+					Detail:             &TransactionEvent{},
+					EventBridgeHandler: m.TransactionUpdated,
+				},
+			},
+		},
+	}
+}
+
+func (m *Manager) GetDynamoDBStreamHandler() map[string]map[string]*eventprocessor.DynamoDBStream {
+	customerStream := &eventprocessor.DynamoDBStream{
+		Image:                 &CustomerRecord{},
+		DynamoDBStreamHandler: m.CustomerChanged,
+	}
+	return map[string]map[string]*eventprocessor.DynamoDBStream{
+		"CUSTOMER": {
+			eventprocessor.DynamoDBInsert: customerStream,
+			eventprocessor.DynamoDBModify: customerStream,
+			eventprocessor.DynamoDBRemove: customerStream,
+		},
+	}
+}
+
 func (m *Manager) GetLambdaHandler() map[string]*eventprocessor.LambdaInvocation {
-	return nil
+	return map[string]*eventprocessor.LambdaInvocation{
+		"ACTION_1": {
+			LambdaHandlerFunc: m.ActionOne,
+		}, "ACTION_2": {
+			LambdaContextHandlerFunc: m.ActionTwo,
+		},
+	}
 }
 
 func (m *Manager) GetSQSEventHandler() map[string]*eventprocessor.SQS {
 	t := &eventprocessor.SQS{
 		SQSHandler: m.test_func,
 	}
-	new := map[string]*eventprocessor.SQS{"TEST_QUEUE": t}
+	batch := &eventprocessor.SQS{
+		SQSRecordContextHandler: m.ProcessMessage,
+	}
+	new := map[string]*eventprocessor.SQS{"TEST_QUEUE": t, "TEST_BATCH_QUEUE": batch}
 	return new
 }
 
@@ -129,12 +162,20 @@ func (m *Manager) test_func(body interface{}) error {
 	return nil
 }
 
+func (m *Manager) ProcessMessage(ctx context.Context, record *events.SQSMessage) error {
+	eventprocessor.GetLogger(ctx).Info("SQS message", record.Body)
+	if record.Body == "" {
+		return utils.NewHTTPBadRequestError("empty message", record.MessageId)
+	}
+	return nil
+}
+
 func (m *Manager) GetCronHandler() map[string]*eventprocessor.CronInvocation {
 	return map[string]*eventprocessor.CronInvocation{
 		"CRON_ACTION_1": {
 			CronHandlerFunc: m.CronActionOne,
 		}, "CRON_ACTION_2": {
-			CronHandlerFunc: m.CronActionTwo,
+			CronContextHandlerFunc: m.CronActionTwo,
 		},
 	}
 }
@@ -234,12 +275,9 @@ func (m *Manager) Post(body interface{}, pathParam interface{}, jsonBody string,
 
 }
 
-func (m *Manager) Upload(body interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
-	m.log.Info("Body", body)
-	req, ok := body.(*Upload)
-	if !ok {
-		panic(utils.NewHTTPBadRequestError("Error in reading payload", body))
-	}
+func (m *Manager) Upload(request *UploadRequest) (int, map[string]interface{}, error) {
+	m.log.Info("Body", request.Body)
+	req := request.Body
 	apiClient := http.NewHTTPClient(m.ctx)
 	res, responseBody, err := apiClient.Get(req.URL, nil, nil, 10)
 	if err != nil {
@@ -258,12 +296,25 @@ func (m *Manager) Upload(body interface{}, pathParam interface{}, jsonBody strin
 
 }
 
-func (m *Manager) UploadPII(body interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
-	m.log.Info("Body", body)
-	req, ok := body.(*Upload)
-	if !ok {
-		panic(utils.NewHTTPBadRequestError("Error in reading payload", body))
+func (m *Manager) UploadFile(request *FileUploadRequest) (int, map[string]interface{}, error) {
+	file := request.Body.File
+	m.log.Info("File", file)
+	s3Client := aws.GetDefaultS3Client(m.ctx)
+	s3Bucker := "mfcore-data"
+	path := fmt.Sprintf("dev/temp/gobasetest/%v-%v", uuid.NewString(), file.FileName)
+	err := s3Client.PutObject(s3Bucker, path, file.Open(), file.ContentType)
+	if err != nil {
+		return 500, nil, err
 	}
+	return 200, map[string]interface{}{
+		"uploadPath": path,
+	}, nil
+
+}
+
+func (m *Manager) UploadPII(request *UploadRequest) (int, map[string]interface{}, error) {
+	m.log.Info("Body", request.Body)
+	req := request.Body
 	apiClient := http.NewHTTPClient(m.ctx)
 	res, responseBody, err := apiClient.Get(req.URL, nil, nil, 10)
 	if err != nil {
@@ -290,12 +341,9 @@ func (m *Manager) UploadPII(body interface{}, pathParam interface{}, jsonBody st
 
 }
 
-func (m *Manager) DownloadPII(body interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
-	m.log.Info("Body", body)
-	req, ok := body.(*Upload)
-	if !ok {
-		panic(utils.NewHTTPBadRequestError("Error in reading payload", body))
-	}
+func (m *Manager) DownloadPII(request *UploadRequest) (int, interface{}, error) {
+	m.log.Info("Body", request.Body)
+	req := request.Body
 	kms := "arn:aws:kms:ap-south-1:490302598154:key/489a37f4-4ef0-408f-9ecf-7dd629505060"
 	s3Client, err := aws.GetDefaultS3PIIClient(m.ctx, kms)
 	if err != nil {
@@ -310,12 +358,9 @@ func (m *Manager) DownloadPII(body interface{}, pathParam interface{}, jsonBody
 
 }
 
-func (m *Manager) Download(body interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
-	m.log.Info("Body", body)
-	req, ok := body.(*Upload)
-	if !ok {
-		panic(utils.NewHTTPBadRequestError("Error in reading payload", body))
-	}
+func (m *Manager) Download(request *UploadRequest) (int, interface{}, error) {
+	m.log.Info("Body", request.Body)
+	req := request.Body
 	s3Client := aws.GetDefaultS3Client(m.ctx)
 	s3Bucker := "mfcore-data"
 	piiCache, err := s3Client.CreatePresignedURLGET(s3Bucker, req.FileName, 60*15)
@@ -326,6 +371,22 @@ func (m *Manager) Download(body interface{}, pathParam interface{}, jsonBody str
 
 }
 
+func (m *Manager) DownloadFile(request *UploadRequest) (int, interface{}, error) {
+	m.log.Info("Body", request.Body)
+	req := request.Body
+	s3Client := aws.GetDefaultS3Client(m.ctx)
+	s3Bucker := "mfcore-data"
+	file, err := s3Client.GetObject(s3Bucker, req.FileName)
+	if err != nil {
+		return 500, nil, err
+	}
+	return 200, &eventprocessor.FileResponse{
+		Body:     file,
+		FileName: filepath.Base(req.FileName),
+	}, nil
+
+}
+
 func (m *Manager) StepFuncInvocation(queryParam interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
 	sfnPayload := map[string]interface{}{
 		"isLambdaInvocation": true,
@@ -355,6 +416,30 @@ func (m *Manager) TransactionConfirmed(payload interface{}) error {
 	return nil
 }
 
+func (m *Manager) TransactionUpdateRejected(ctx context.Context, event *events.CloudWatchEvent, detail interface{}) error {
+	transaction := detail.(*TransactionEvent)
+	eventprocessor.GetLogger(ctx).Info("TransactionUpdateRejected", transaction)
+	return nil
+}
+
+func (m *Manager) TransactionUpdated(ctx context.Context, event *events.CloudWatchEvent, detail interface{}) error {
+	transaction := detail.(*TransactionEvent)
+	eventprocessor.GetLogger(ctx).Info("TransactionUpdated", transaction)
+	return nil
+}
+
+func (m *Manager) CustomerChanged(ctx context.Context, record *events.DynamoDBEventRecord, newImage, oldImage interface{}) error {
+	logger := eventprocessor.GetLogger(ctx)
+	logger.Info("CustomerChanged", record.EventName)
+	if customer, ok := newImage.(*CustomerRecord); ok {
+		logger.Info("CustomerChanged new image", customer)
+	}
+	if customer, ok := oldImage.(*CustomerRecord); ok {
+		logger.Info("CustomerChanged old image", customer)
+	}
+	return nil
+}
+
 func (m *Manager) ActionOne(payload interface{}) (int, interface{}, error) {
 	m.log.Info("LambdaInvocationActionOne", payload)
 	return 200, map[string]interface{}{
@@ -366,8 +451,8 @@ func (m *Manager) ActionOne(payload interface{}) (int, interface{}, error) {
 	}, nil
 }
 
-func (m *Manager) ActionTwo(payload interface{}) (int, interface{}, error) {
-	m.log.Info("LambdaInvocationActionTwo", payload)
+func (m *Manager) ActionTwo(ctx context.Context, payload interface{}) (int, interface{}, error) {
+	eventprocessor.GetLogger(ctx).Info("LambdaInvocationActionTwo", payload)
 	return 200, map[string]string{"ACTION": "TWO"}, nil
 }
 
@@ -376,8 +461,12 @@ func (m *Manager) CronActionOne(payload interface{}) (int, interface{}, error) {
 	return 200, map[string]string{"ACTION": "ONE"}, nil
 }
 
-func (m *Manager) CronActionTwo(payload interface{}) (int, interface{}, error) {
-	m.log.Info("CronActionTwo", payload)
+func (m *Manager) CronActionTwo(ctx context.Context, payload interface{}) (int, interface{}, error) {
+	logger := eventprocessor.GetLogger(ctx)
+	logger.Info("CronActionTwo", payload)
+	if scheduledTime, ok := eventprocessor.GetScheduledTime(ctx); ok {
+		logger.Info("CronActionTwo scheduled time", scheduledTime)
+	}
 	return 200, map[string]string{"ACTION": "TWO"}, nil
 }
 
@@ -1,5 +1,7 @@
 package example
 
+import "gobase-lambda/eventprocessor"
+
 type Address struct {
 	AddressLine1 string
 	AddressLine2 string
@@ -7,8 +9,8 @@ type Address struct {
 }
 
 type Payload struct {
-	Name    string
-	Gender  string
+	Name    string `validate:"required,max=100"`
+	Gender  string `validate:"enum=M|F|O"`
 	Address Address
 }
 
@@ -17,6 +19,15 @@ type Upload struct {
 	FileName string
 }
 
+type UploadRequest = eventprocessor.Request[Upload, eventprocessor.Empty, eventprocessor.Empty]
+
+type FileUpload struct {
+	FileName string                   `json:"fileName"`
+	File     *eventprocessor.FilePart `json:"file" validate:"required"`
+}
+
+type FileUploadRequest = eventprocessor.Request[FileUpload, eventprocessor.Empty, eventprocessor.Empty]
+
 type QueryParams struct {
 	Status  []string `structs:"status" json:"status"`
 	Name    string   `structs:"name" json:"name"`
@@ -28,3 +39,16 @@ type QueryParams struct {
 type PathParams struct {
 	CustomerId string
 }
+
+type TransactionEvent struct {
+	TransactionId string  `json:"transactionId" validate:"required"`
+	Status        string  `json:"status" validate:"enum=CONFIRMED|REJECTED"`
+	Amount        float64 `json:"amount" validate:"min=0"`
+}
+
+type CustomerRecord struct {
+	CustomerId string            `json:"customerId"`
+	Name       string            `json:"name"`
+	Tags       []string          `json:"tags"`
+	Attributes map[string]string `json:"attributes"`
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+	"gobase-lambda/log"
+)
+
+// Writes the OpenAPI document of the API routes, run it with go run ./example/openapi -out openapi.json
+func main() {
+	out := flag.String("out", "openapi.json", "path of the generated document")
+	version := flag.String("version", "1.0.0", "version of the API")
+	flag.Parse()
+	handler := eventprocessor.GetOfflineHandler(example.NewManager)
+	handler.MustValidateRoutes()
+	document := handler.OpenAPI(eventprocessor.OpenAPIInfo{Title: "gobase example API", Version: *version})
+	if err := document.WriteFile(*out); err != nil {
+		panic(err)
+	}
+	log.GetDefaultLogger().Info("OpenAPI document written", *out)
+}
@@ -9,6 +9,7 @@ import (
 
 func main() {
 	handler := eventprocessor.GetHandler(true, example.NewManager)
+	handler.MustValidateRoutes()
 	logger := log.GetDefaultLogger()
 	logger.Info("Lambda initiated", nil)
 	lambda.Start(handler.HandleSNSRequest)
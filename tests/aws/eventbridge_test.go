@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gobase-lambda/aws"
+)
+
+func TestEventBridgeClient(t *testing.T) {
+	eventBridgeClient := aws.GetDefaultEventBridgeClient(context.TODO())
+	entries := make([]*aws.EventBridgeEntry, 0)
+	for i := 0; i < 15; i++ {
+		entries = append(entries, &aws.EventBridgeEntry{
+			EventBusName: aws.GetEventBusName("MFCORE"),
+			Source:       "mfcore.payment",
+			DetailType:   "Transaction Updated",
+			Detail: map[string]interface{}{
+				"transactionId": fmt.Sprintf("TXN%03d", i),
+				"status":        "CONFIRMED",
+				"amount":        100,
+			},
+		})
+	}
+	res, err := eventBridgeClient.PutEvents(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != len(entries) {
+		t.Fatal("unexpected result entries", res)
+	}
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+)
+
+type tagsQuery struct {
+	Tags []string `json:"tags"`
+}
+
+func albHandler() *eventprocessor.Handler {
+	router := eventprocessor.NewRouter()
+	router.GET("/orders/{id}", eventprocessor.Route(func(request *eventprocessor.Request[eventprocessor.Empty, tagsQuery, order]) (int, events.APIGatewayProxyResponse, error) {
+		return 200, events.APIGatewayProxyResponse{
+			StatusCode:        200,
+			Headers:           map[string]string{"Content-Type": "text/plain"},
+			MultiValueHeaders: map[string][]string{"Set-Cookie": {"a=1", "b=2"}},
+			Body:              request.PathParams.ID + " " + strings.Join(request.QueryParams.Tags, "|"),
+		}, nil
+	}))
+	return newHandler(&processor{api: router.MustBuild()})
+}
+
+func albRequest(path string) events.ALBTargetGroupRequest {
+	return events.ALBTargetGroupRequest{
+		HTTPMethod:     http.MethodGet,
+		Path:           path,
+		RequestContext: events.ALBTargetGroupRequestContext{ELB: events.ELBContext{TargetGroupArn: "arn:aws:elasticloadbalancing:ap-south-1:123456789012:targetgroup/orders/1"}},
+	}
+}
+
+func TestALBRequest(t *testing.T) {
+	request := albRequest("/orders/42")
+	request.Headers = map[string]string{"accept": "text/plain"}
+	request.QueryStringParameters = map[string]string{"tags": "new%20items"}
+	response, err := albHandler().HandleALBRequest(context.TODO(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 200)
+	switch {
+	case response.StatusDescription != "200 OK":
+		t.Fatal("unexpected status description", response.StatusDescription)
+	case response.Body != "42 new items":
+		t.Fatal("unexpected body", response.Body)
+	case response.MultiValueHeaders != nil:
+		t.Fatal("multi value headers returned for a single value request", response.MultiValueHeaders)
+	case response.Headers["Content-Type"] != "text/plain" || response.Headers["Set-Cookie"] != "b=2":
+		t.Fatal("unexpected headers", response.Headers)
+	}
+}
+
+func TestALBMultiValueHeaders(t *testing.T) {
+	request := albRequest("/orders/42")
+	request.MultiValueHeaders = map[string][]string{"accept": {"text/plain"}}
+	request.MultiValueQueryStringParameters = map[string][]string{"tags": {"new", "gift%20wrap"}}
+	response, err := albHandler().HandleALBRequest(context.TODO(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 200)
+	switch {
+	case response.Body != "42 new|gift wrap":
+		t.Fatal("unexpected body", response.Body)
+	case response.Headers != nil:
+		t.Fatal("single value headers returned for a multi value request", response.Headers)
+	case strings.Join(response.MultiValueHeaders["Set-Cookie"], ",") != "a=1,b=2":
+		t.Fatal("unexpected cookies", response.MultiValueHeaders)
+	case strings.Join(response.MultiValueHeaders["Content-Type"], ",") != "text/plain":
+		t.Fatal("unexpected content type", response.MultiValueHeaders)
+	}
+}
+
+func TestALBUnknownPath(t *testing.T) {
+	response, _ := albHandler().HandleALBRequest(context.TODO(), albRequest("/customers/42"))
+	assertStatus(t, response.StatusCode, 404)
+	if response.StatusDescription != "404 Not Found" {
+		t.Fatal("unexpected status description", response.StatusDescription)
+	}
+}
@@ -0,0 +1,282 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+	"gobase-lambda/eventprocessor"
+)
+
+type searchQuery struct {
+	Name     string        `json:"name" default:"a,b"`
+	Status   []string      `json:"status" default:"open,closed"`
+	Ids      []int         `json:"ids"`
+	From     time.Time     `json:"from"`
+	Timeout  time.Duration `json:"timeout" default:"30s"`
+	IP       net.IP        `json:"ip"`
+	PageSize *int          `json:"pageSize"`
+	Offset   *int          `json:"offset"`
+	Active   bool          `json:"active" default:"true"`
+}
+
+type searchRequest = eventprocessor.Request[eventprocessor.Empty, searchQuery, eventprocessor.Empty]
+
+func searchHandler() *eventprocessor.Handler {
+	router := eventprocessor.NewRouter()
+	router.GET("/search", eventprocessor.Route(func(request *searchRequest) (int, *searchQuery, error) {
+		return 200, request.QueryParams, nil
+	}))
+	return newHandler(&processor{api: router.MustBuild()})
+}
+
+func search(t *testing.T, queryParams map[string][]string) (events.APIGatewayProxyResponse, error) {
+	t.Helper()
+	request := events.APIGatewayProxyRequest{
+		HTTPMethod:                      "GET",
+		Resource:                        "/search",
+		Path:                            "/search",
+		QueryStringParameters:           map[string]string{},
+		MultiValueQueryStringParameters: queryParams,
+	}
+	for key, values := range queryParams {
+		request.QueryStringParameters[key] = values[len(values)-1]
+	}
+	return searchHandler().HandleAPIRequest(context.TODO(), request)
+}
+
+func TestQueryParamsBinding(t *testing.T) {
+	response, err := search(t, map[string][]string{
+		"ids":      {"1", "2"},
+		"from":     {"2024-01-02"},
+		"timeout":  {"1m30s"},
+		"ip":       {"10.0.0.1"},
+		"pageSize": {"50"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 200)
+	query := searchQuery{}
+	if err = json.Unmarshal([]byte(response.Body), &query); err != nil {
+		t.Fatal(err)
+	}
+	switch {
+	case query.Name != "a,b":
+		t.Fatal("scalar default was split", query.Name)
+	case strings.Join(query.Status, "|") != "open|closed":
+		t.Fatal("slice default was not split", query.Status)
+	case len(query.Ids) != 2 || query.Ids[1] != 2:
+		t.Fatal("unexpected ids", query.Ids)
+	case !query.From.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)):
+		t.Fatal("unexpected from", query.From)
+	case query.Timeout != 90*time.Second:
+		t.Fatal("unexpected timeout", query.Timeout)
+	case !query.IP.Equal(net.ParseIP("10.0.0.1")):
+		t.Fatal("unexpected ip", query.IP)
+	case query.PageSize == nil || *query.PageSize != 50 || query.Offset != nil:
+		t.Fatal("unexpected pointers", query.PageSize, query.Offset)
+	case !query.Active:
+		t.Fatal("bool default was not applied")
+	}
+}
+
+func TestQueryParamsBindingDefaults(t *testing.T) {
+	response, err := search(t, map[string][]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := searchQuery{}
+	if err = json.Unmarshal([]byte(response.Body), &query); err != nil {
+		t.Fatal(err)
+	}
+	if query.Timeout != 30*time.Second || query.Name != "a,b" || len(query.Status) != 2 {
+		t.Fatalf("defaults not applied %+v", query)
+	}
+}
+
+func TestQueryParamsBindingErrors(t *testing.T) {
+	tests := []struct {
+		field string
+		value string
+	}{
+		{"ids", "one"},
+		{"from", "yesterday"},
+		{"timeout", "30"},
+		{"ip", "10.0.0"},
+		{"pageSize", "1.5"},
+		{"active", "maybe"},
+	}
+	for _, test := range tests {
+		t.Run(test.field, func(t *testing.T) {
+			response, err := search(t, map[string][]string{test.field: {test.value}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStatus(t, response.StatusCode, 400)
+			problem := struct {
+				ErrorCode string                      `json:"errorCode"`
+				ErrorData []eventprocessor.FieldError `json:"errorData"`
+			}{}
+			if err = json.Unmarshal([]byte(response.Body), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.ErrorCode != "VALIDATION_FAILED" || len(problem.ErrorData) != 1 {
+				t.Fatal("unexpected problem", response.Body)
+			}
+			if fieldError := problem.ErrorData[0]; fieldError.Field != test.field || fieldError.In != "query" || !strings.HasPrefix(fieldError.Reason, "invalid value") {
+				t.Fatalf("unexpected field error %+v", fieldError)
+			}
+		})
+	}
+}
+
+func TestQueryParamsBindingIsValidatedOnBuild(t *testing.T) {
+	tests := []struct {
+		name       string
+		queryParam interface{}
+		problem    string
+	}{
+		{"unsupported type", &struct {
+			Filter map[string]string `json:"filter"`
+		}{}, "unsupported query param type"},
+		{"pointer to slice", &struct {
+			Ids *[]int `json:"ids"`
+		}{}, "unsupported query param type"},
+		{"invalid default", &struct {
+			PageSize int `json:"pageSize" default:"twenty"`
+		}{}, "invalid default"},
+		{"invalid slice default", &struct {
+			Ids []int `json:"ids" default:"1,two"`
+		}{}, "invalid default"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := eventprocessor.NewRouter()
+			router.GET("/search", &eventprocessor.API{
+				QueryParams: test.queryParam,
+				ApiHandler: func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+					return 200, nil, nil
+				},
+			})
+			_, err := router.Build()
+			if err == nil || !strings.Contains(err.Error(), test.problem) {
+				t.Fatalf("expected %q, got %v", test.problem, err)
+			}
+		})
+	}
+}
+
+type orderPath struct {
+	ID  int       `json:"id"`
+	Ref uuid.UUID `json:"ref"`
+}
+
+func orderPathRequest(t *testing.T, id, ref string) events.APIGatewayProxyResponse {
+	t.Helper()
+	router := eventprocessor.NewRouter()
+	router.GET("/orders/{id}/{ref}", eventprocessor.Route(func(request *eventprocessor.Request[eventprocessor.Empty, eventprocessor.Empty, orderPath]) (int, *orderPath, error) {
+		return 200, request.PathParams, nil
+	}))
+	request := events.APIGatewayProxyRequest{
+		HTTPMethod:     "GET",
+		Resource:       "/orders/{id}/{ref}",
+		Path:           "/orders/" + id + "/" + ref,
+		PathParameters: map[string]string{"id": id, "ref": ref},
+	}
+	response, err := newHandler(&processor{api: router.MustBuild()}).HandleAPIRequest(context.TODO(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestPathParamsBinding(t *testing.T) {
+	ref := "7d444840-9dc0-11d1-b245-5ffdce74fad2"
+	response := orderPathRequest(t, "42", ref)
+	assertStatus(t, response.StatusCode, 200)
+	path := orderPath{}
+	if err := json.Unmarshal([]byte(response.Body), &path); err != nil {
+		t.Fatal(err)
+	}
+	if path.ID != 42 || path.Ref.String() != ref {
+		t.Fatalf("unexpected path params %+v", path)
+	}
+}
+
+func TestPathParamsBindingErrors(t *testing.T) {
+	tests := []struct {
+		field string
+		id    string
+		ref   string
+	}{
+		{"id", "abc", "7d444840-9dc0-11d1-b245-5ffdce74fad2"},
+		{"ref", "42", "not-a-uuid"},
+	}
+	for _, test := range tests {
+		t.Run(test.field, func(t *testing.T) {
+			response := orderPathRequest(t, test.id, test.ref)
+			assertStatus(t, response.StatusCode, 400)
+			problem := struct {
+				ErrorCode string                      `json:"errorCode"`
+				ErrorData []eventprocessor.FieldError `json:"errorData"`
+			}{}
+			if err := json.Unmarshal([]byte(response.Body), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.ErrorCode != "VALIDATION_FAILED" || len(problem.ErrorData) != 1 {
+				t.Fatal("unexpected problem", response.Body)
+			}
+			if fieldError := problem.ErrorData[0]; fieldError.Field != test.field || fieldError.In != "path" {
+				t.Fatalf("unexpected field error %+v", fieldError)
+			}
+		})
+	}
+}
+
+func TestPathParamsBindingIsValidatedOnBuild(t *testing.T) {
+	tests := []struct {
+		name      string
+		pathParam interface{}
+	}{
+		{"unsupported type", &struct {
+			ID map[string]string `json:"id"`
+		}{}},
+		{"slice", &struct {
+			ID []int `json:"id"`
+		}{}},
+		{"struct", &struct {
+			ID struct{ Value int } `json:"id"`
+		}{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := eventprocessor.NewRouter()
+			router.GET("/orders/{id}", &eventprocessor.API{
+				PathParams: test.pathParam,
+				ApiHandler: func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+					return 200, nil, nil
+				},
+			})
+			_, err := router.Build()
+			if err == nil || !strings.Contains(err.Error(), "unsupported path param type") {
+				t.Fatal("expected an unsupported path param type, got", err)
+			}
+		})
+	}
+	router := eventprocessor.NewRouter()
+	router.GET("/orders/{id}/{ref}", &eventprocessor.API{
+		PathParams: &orderPath{},
+		ApiHandler: func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+			return 200, nil, nil
+		},
+	})
+	if _, err := router.Build(); err != nil {
+		t.Fatal(err)
+	}
+}
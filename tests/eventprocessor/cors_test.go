@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+)
+
+func TestCORSRejectsAnyOriginWithCredentials(t *testing.T) {
+	config := &eventprocessor.CORSConfig{AllowedOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true}
+	if err := config.Validate(); err == nil {
+		t.Fatal("expected the * origin with credentials to be rejected")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected SetCORS to panic")
+		}
+	}()
+	newHandler(&processor{api: loggingRouter()}).SetCORS(config)
+}
+
+func TestCORSAllowOrigin(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *eventprocessor.CORSConfig
+		origin      string
+		allowOrigin string
+		credentials string
+	}{
+		{"any origin", &eventprocessor.CORSConfig{AllowedOrigins: []string{"*"}}, "https://evil.example.org", "*", ""},
+		{"pattern with credentials", &eventprocessor.CORSConfig{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true}, "https://app.example.com", "https://app.example.com", "true"},
+		{"origin not allowed", &eventprocessor.CORSConfig{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true}, "https://evil.example.org", "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := newHandler(&processor{api: loggingRouter()})
+			handler.SetCORS(test.config)
+			request := events.APIGatewayProxyRequest{
+				HTTPMethod: "GET",
+				Resource:   "/orders",
+				Path:       "/orders",
+				Headers:    map[string]string{"Origin": test.origin},
+			}
+			response, err := handler.HandleAPIRequest(context.TODO(), request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStatus(t, response.StatusCode, 200)
+			if response.Headers["Access-Control-Allow-Origin"] != test.allowOrigin {
+				t.Fatalf("allow origin %q, expected %q", response.Headers["Access-Control-Allow-Origin"], test.allowOrigin)
+			}
+			if response.Headers["Access-Control-Allow-Credentials"] != test.credentials {
+				t.Fatalf("allow credentials %q, expected %q", response.Headers["Access-Control-Allow-Credentials"], test.credentials)
+			}
+		})
+	}
+}
+
+func corsHandler() *eventprocessor.Handler {
+	router := eventprocessor.NewRouter()
+	router.GET("/orders/{id}", func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+		return 200, pathParam, nil
+	})
+	router.DELETE("/orders/{id}", func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+		return 500, nil, errHandler
+	})
+	router.OPTIONS("/customers", func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+		return 200, map[string]string{"route": "options"}, nil
+	})
+	handler := newHandler(&processor{api: router.MustBuild()})
+	handler.SetCORS(&eventprocessor.CORSConfig{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		ExposedHeaders:   []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	})
+	return handler
+}
+
+func preflight(resource, path, origin string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{
+		HTTPMethod: "OPTIONS",
+		Resource:   resource,
+		Path:       path,
+		Headers: map[string]string{
+			"Origin":                         origin,
+			"Access-Control-Request-Method":  "DELETE",
+			"Access-Control-Request-Headers": "authorization, content-type",
+		},
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	response, err := corsHandler().HandleAPIRequest(context.TODO(), preflight("/orders/{id}", "/orders/1", "https://app.example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 204)
+	expected := map[string]string{
+		"Vary":                             "Origin",
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Methods":     "DELETE, GET",
+		"Access-Control-Allow-Headers":     "authorization, content-type",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "600",
+	}
+	for key, value := range expected {
+		if response.Headers[key] != value {
+			t.Fatalf("header %v is %q, expected %q", key, response.Headers[key], value)
+		}
+	}
+}
+
+func TestCORSPreflightFromRawPath(t *testing.T) {
+	response, err := corsHandler().HandleAPIRequest(context.TODO(), preflight("", "/orders/1", "https://app.example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 204)
+	if response.Headers["Access-Control-Allow-Methods"] != "DELETE, GET" {
+		t.Fatal("unexpected allowed methods", response.Headers)
+	}
+}
+
+func TestCORSPreflightOriginNotAllowed(t *testing.T) {
+	response, err := corsHandler().HandleAPIRequest(context.TODO(), preflight("/orders/{id}", "/orders/1", "https://evil.example.org"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 204)
+	if response.Headers["Access-Control-Allow-Origin"] != "" || response.Headers["Access-Control-Allow-Methods"] != "" {
+		t.Fatal("expected no CORS headers for a disallowed origin", response.Headers)
+	}
+}
+
+func TestCORSPreflightKeepsOptionsRoute(t *testing.T) {
+	response, err := corsHandler().HandleAPIRequest(context.TODO(), preflight("/customers", "/customers", "https://app.example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 200)
+	if !strings.Contains(response.Body, `"route":"options"`) {
+		t.Fatal("expected the registered OPTIONS route to answer", response.Body)
+	}
+	if response.Headers["Access-Control-Allow-Origin"] != "https://app.example.com" {
+		t.Fatal("expected the CORS headers on the OPTIONS route response", response.Headers)
+	}
+}
+
+func TestCORSHeadersOnErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		statusCode int
+	}{
+		{"handler error", "DELETE", "/orders/1", 500},
+		{"unknown path", "GET", "/invoices/1", 404},
+		{"unknown preflight", "OPTIONS", "/invoices/1", 404},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := preflight("", test.path, "https://app.example.com")
+			request.HTTPMethod = test.method
+			response, _ := corsHandler().HandleAPIRequest(context.TODO(), request)
+			assertStatus(t, response.StatusCode, test.statusCode)
+			switch {
+			case response.Headers["Access-Control-Allow-Origin"] != "https://app.example.com":
+				t.Fatal("expected the allowed origin on the error response", response.Headers)
+			case response.Headers["Access-Control-Allow-Credentials"] != "true":
+				t.Fatal("expected credentials on the error response", response.Headers)
+			case response.Headers["Access-Control-Expose-Headers"] != "X-Request-Id":
+				t.Fatal("expected the exposed headers on the error response", response.Headers)
+			case !strings.Contains(response.Headers["Vary"], "Origin"):
+				t.Fatal("expected Vary: Origin on the error response", response.Headers)
+			}
+		})
+	}
+}
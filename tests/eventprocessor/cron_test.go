@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"gobase-lambda/eventprocessor"
+)
+
+// cronCall records the payload and the scheduled time the REPORT action is called with.
+type cronCall struct {
+	payload       interface{}
+	scheduledTime time.Time
+	hasTime       bool
+}
+
+func cronHandler(call *cronCall) *eventprocessor.Handler {
+	return newHandler(&processor{cron: map[string]*eventprocessor.CronInvocation{
+		"REPORT": {CronContextHandlerFunc: func(ctx context.Context, payload interface{}) (int, interface{}, error) {
+			call.payload = payload
+			call.scheduledTime, call.hasTime = eventprocessor.GetScheduledTime(ctx)
+			return 200, map[string]string{"status": "done"}, nil
+		}},
+	}})
+}
+
+func cronEvent(t *testing.T, payload string) eventprocessor.CronEvent {
+	t.Helper()
+	event := eventprocessor.CronEvent{}
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func TestCronEvents(t *testing.T) {
+	scheduledTime := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		event   string
+		payload interface{}
+		hasTime bool
+	}{
+		// the input of an EventBridge Scheduler schedule with the scheduled-time context attribute
+		{"scheduler input", `{"isCron":true,"actionName":"REPORT","scheduledTime":"2026-10-18T00:00:00Z","payload":{"window":"1d"}}`,
+			map[string]interface{}{"window": "1d"}, true},
+		{"custom payload", `{"isCron":true,"actionName":"REPORT","payload":"daily"}`, "daily", false},
+		{"rule event", `{"version":"0","id":"53dc4d37-cffa-4f76-80c9-8b7d4a4d2eaa","detail-type":"Scheduled Event","source":"aws.events",
+			"account":"123456789012","time":"2026-10-18T00:00:00Z","region":"ap-south-1",
+			"resources":["arn:aws:events:ap-south-1:123456789012:rule/dev_REPORT"],"detail":{}}`, nil, true},
+		{"rule event with detail", `{"version":"0","id":"53dc4d37-cffa-4f76-80c9-8b7d4a4d2eaa","detail-type":"Scheduled Event","source":"aws.events",
+			"account":"123456789012","time":"2026-10-18T00:00:00Z","region":"ap-south-1",
+			"resources":["arn:aws:events:ap-south-1:123456789012:rule/nightly"],"detail":{"actionName":"REPORT","payload":{"window":"1d"}}}`,
+			map[string]interface{}{"window": "1d"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			call := &cronCall{}
+			response, err := cronHandler(call).HandleCronInvocation(context.TODO(), cronEvent(t, test.event))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStatus(t, response.StatusCode, 200)
+			if response.Body != `{"status":"done"}` {
+				t.Fatal("unexpected body", response.Body)
+			}
+			if !reflect.DeepEqual(call.payload, test.payload) {
+				t.Fatalf("payload %#v, expected %#v", call.payload, test.payload)
+			}
+			if call.hasTime != test.hasTime || (test.hasTime && !call.scheduledTime.Equal(scheduledTime)) {
+				t.Fatalf("scheduled time %v %v, expected %v", call.scheduledTime, call.hasTime, test.hasTime)
+			}
+		})
+	}
+}
+
+func TestCronUnmappedAction(t *testing.T) {
+	call := &cronCall{}
+	event := cronEvent(t, `{"version":"0","detail-type":"Scheduled Event","source":"aws.events","time":"2026-10-18T00:00:00Z",
+		"resources":["arn:aws:events:ap-south-1:123456789012:rule/dev_CLEANUP"],"detail":{}}`)
+	response, err := cronHandler(call).HandleCronInvocation(context.TODO(), event)
+	if err == nil {
+		t.Fatal("expected the unmapped rule to fail")
+	}
+	assertStatus(t, response.StatusCode, 404)
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+)
+
+type customerImage struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func streamRecord(table, eventName, sequenceNumber string, newImage map[string]events.DynamoDBAttributeValue) events.DynamoDBEventRecord {
+	return events.DynamoDBEventRecord{
+		EventName:      eventName,
+		EventSourceArn: "arn:aws:dynamodb:ap-south-1:123456789012:table/" + table + "/stream/2024-01-01T00:00:00.000",
+		Change:         events.DynamoDBStreamRecord{SequenceNumber: sequenceNumber, NewImage: newImage},
+	}
+}
+
+func customerStream(handler eventprocessor.DynamoDBStreamHandler) *processor {
+	return &processor{dynamoDB: map[string]map[string]*eventprocessor.DynamoDBStream{
+		"CUSTOMER": {eventprocessor.DynamoDBInsert: {Image: &customerImage{}, DynamoDBStreamHandler: handler}},
+	}}
+}
+
+func assertBatchItemFailures(t *testing.T, response events.DynamoDBEventResponse, err error, sequenceNumbers ...string) {
+	t.Helper()
+	if err != nil {
+		t.Fatal("expected the failures to be reported per record, got", err)
+	}
+	if len(response.BatchItemFailures) != len(sequenceNumbers) {
+		t.Fatalf("batch item failures %+v, expected %v", response.BatchItemFailures, sequenceNumbers)
+	}
+	for i, sequenceNumber := range sequenceNumbers {
+		if response.BatchItemFailures[i].ItemIdentifier != sequenceNumber {
+			t.Fatalf("batch item failures %+v, expected %v", response.BatchItemFailures, sequenceNumbers)
+		}
+	}
+}
+
+func TestDynamoDBStreamStopsAtTheFailingRecord(t *testing.T) {
+	processed := make([]string, 0)
+	handler := newHandler(customerStream(func(ctx context.Context, record *events.DynamoDBEventRecord, newImage, oldImage interface{}) error {
+		processed = append(processed, record.Change.SequenceNumber)
+		if newImage.(*customerImage).Name == "fail" {
+			return errHandler
+		}
+		return nil
+	}))
+	request := events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		streamRecord("dev_CUSTOMER", eventprocessor.DynamoDBInsert, "1", map[string]events.DynamoDBAttributeValue{"name": events.NewStringAttribute("akshay")}),
+		streamRecord("dev_CUSTOMER", eventprocessor.DynamoDBInsert, "2", map[string]events.DynamoDBAttributeValue{"name": events.NewStringAttribute("fail")}),
+		streamRecord("dev_CUSTOMER", eventprocessor.DynamoDBInsert, "3", map[string]events.DynamoDBAttributeValue{"name": events.NewStringAttribute("meera")}),
+	}}
+	response, err := handler.HandleDynamoDBStreamRequest(context.TODO(), request)
+	assertBatchItemFailures(t, response, err, "2")
+	if len(processed) != 2 {
+		t.Fatal("expected the processing to stop at the failing record, processed", processed)
+	}
+}
+
+func TestDynamoDBStreamUnmappedRecord(t *testing.T) {
+	tests := []struct {
+		name   string
+		record events.DynamoDBEventRecord
+	}{
+		{"table", streamRecord("dev_ORDERS", eventprocessor.DynamoDBInsert, "1", nil)},
+		{"stage", streamRecord("prod_CUSTOMER", eventprocessor.DynamoDBInsert, "1", nil)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			called := false
+			handler := newHandler(customerStream(func(ctx context.Context, record *events.DynamoDBEventRecord, newImage, oldImage interface{}) error {
+				called = true
+				return nil
+			}))
+			response, err := handler.HandleDynamoDBStreamRequest(context.TODO(), events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{test.record}})
+			assertBatchItemFailures(t, response, err, "1")
+			if called {
+				t.Fatal("handler ran for an unmapped record")
+			}
+		})
+	}
+}
+
+func TestDynamoDBStreamSkipsUnmappedEvents(t *testing.T) {
+	processed := make([]string, 0)
+	handler := newHandler(customerStream(func(ctx context.Context, record *events.DynamoDBEventRecord, newImage, oldImage interface{}) error {
+		processed = append(processed, record.Change.SequenceNumber)
+		return nil
+	}))
+	request := events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		streamRecord("dev_CUSTOMER", eventprocessor.DynamoDBModify, "1", map[string]events.DynamoDBAttributeValue{"name": events.NewStringAttribute("akshay")}),
+		streamRecord("dev_CUSTOMER", eventprocessor.DynamoDBRemove, "2", nil),
+		streamRecord("dev_CUSTOMER", eventprocessor.DynamoDBInsert, "3", map[string]events.DynamoDBAttributeValue{"name": events.NewStringAttribute("meera")}),
+	}}
+	response, err := handler.HandleDynamoDBStreamRequest(context.TODO(), request)
+	assertBatchItemFailures(t, response, err)
+	if len(processed) != 1 || processed[0] != "3" {
+		t.Fatal("expected only the INSERT to be handled, processed", processed)
+	}
+}
+
+func TestDynamoDBStreamImageUnmarshalFailure(t *testing.T) {
+	called := false
+	handler := newHandler(customerStream(func(ctx context.Context, record *events.DynamoDBEventRecord, newImage, oldImage interface{}) error {
+		called = true
+		return nil
+	}))
+	request := events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		streamRecord("dev_CUSTOMER", eventprocessor.DynamoDBInsert, "1", map[string]events.DynamoDBAttributeValue{"age": events.NewStringAttribute("thirty")}),
+	}}
+	response, err := handler.HandleDynamoDBStreamRequest(context.TODO(), request)
+	assertBatchItemFailures(t, response, err, "1")
+	if called {
+		t.Fatal("handler ran with an invalid image")
+	}
+}
+
+func TestDynamoDBStreamTablePrefix(t *testing.T) {
+	t.Setenv("tablePrefix", "CORE")
+	var image *customerImage
+	handler := newHandler(customerStream(func(ctx context.Context, record *events.DynamoDBEventRecord, newImage, oldImage interface{}) error {
+		image = newImage.(*customerImage)
+		if oldImage != nil {
+			return errors.New("unexpected old image")
+		}
+		return nil
+	}))
+	request := events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		streamRecord("dev_CORE_CUSTOMER", eventprocessor.DynamoDBInsert, "1", map[string]events.DynamoDBAttributeValue{
+			"name": events.NewStringAttribute("akshay"),
+			"age":  events.NewNumberAttribute("30"),
+		}),
+	}}
+	response, err := handler.HandleDynamoDBStreamRequest(context.TODO(), request)
+	assertBatchItemFailures(t, response, err)
+	if image == nil || image.Name != "akshay" || image.Age != 30 {
+		t.Fatalf("unexpected image %+v", image)
+	}
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/log"
+)
+
+// invocationLogger replaces the logger of the invocation with one printing the correlation id.
+func invocationLogger(correlationId string) eventprocessor.Middleware {
+	return eventprocessor.MiddlewareFunc(func(invocation *eventprocessor.Invocation, next eventprocessor.Next) (interface{}, error) {
+		invocation.Log = log.NewLogger(true, log.DEBUG, map[string]string{"x-correlation-id": correlationId})
+		return next(invocation)
+	})
+}
+
+// assertLoggedWith checks that the log lines with the message carry the correlation id.
+func assertLoggedWith(t *testing.T, output, message, correlationId string) {
+	t.Helper()
+	found := false
+	for _, line := range strings.Split(output, "\n") {
+		entry := map[string]interface{}{}
+		if json.Unmarshal([]byte(line), &entry) != nil || !strings.Contains(entry["short_message"].(string), message) {
+			continue
+		}
+		found = true
+		if entry["_x_correlation_id"] != correlationId {
+			t.Fatalf("%v is logged without the invocation logger: %v", message, line)
+		}
+	}
+	if !found {
+		t.Fatalf("%v is not logged by the invocation logger:\n%v", message, output)
+	}
+}
+
+func TestEventBridgeLogsWithTheInvocationLogger(t *testing.T) {
+	handler := newHandler(&processor{eventBridge: map[string]map[string][]*eventprocessor.EventBridge{
+		"orders": {"Order Placed": {{
+			Match:              map[string]interface{}{"$.channel": "web"},
+			EventBridgeHandler: func(ctx context.Context, event *events.CloudWatchEvent, detail interface{}) error { return nil },
+		}}},
+	}})
+	handler.UseFor(eventprocessor.EventEventBridge, invocationLogger("eb-1"))
+	var err error
+	output := captureOutput(t, func() {
+		_, err = handler.HandleEventBridgeRequest(context.TODO(), events.CloudWatchEvent{Source: "orders", DetailType: "Order Placed", Detail: []byte(`{"channel":"web"}`)})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLoggedWith(t, output, "Source", "eb-1")
+	assertLoggedWith(t, output, "Detail Type", "eb-1")
+	output = captureOutput(t, func() {
+		_, err = handler.HandleEventBridgeRequest(context.TODO(), events.CloudWatchEvent{Source: "orders", DetailType: "Order Placed", Detail: []byte(`{"channel":"app"}`)})
+	})
+	if err == nil {
+		t.Fatal("expected the unmatched event to fail")
+	}
+	assertLoggedWith(t, output, "does not match any handler", "eb-1")
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/log"
+	"gobase-lambda/utils"
+)
+
+// dispatchHandler records the event type the event processor is created for and the handler called.
+func dispatchHandler(eventTypes *[]eventprocessor.EventType, called *string) *eventprocessor.Handler {
+	router := eventprocessor.NewRouter()
+	router.GET("/orders", func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+		*called = "api"
+		return 200, map[string]string{"status": "ok"}, nil
+	})
+	p := &processor{
+		api: router.MustBuild(),
+		sns: map[string]map[string]*eventprocessor.SNS{"dev_PAYMENT": {"transaction.failed": {SnsHandler: func(payload interface{}) error {
+			*called = "sns"
+			return nil
+		}}}},
+		sqs: map[string]*eventprocessor.SQS{
+			"ORDERS": {SQSHandler: func(payload interface{}) error {
+				*called = "sqs"
+				return nil
+			}},
+			"PAYMENTS": {SQSRecordHandler: func(record *events.SQSMessage) error {
+				*called = "sqs record"
+				return nil
+			}},
+		},
+		s3: map[string]map[string]map[string]*eventprocessor.S3Trigger{"bucket": {"ObjectCreated:Put": {"uploads/": {S3TriggerHandler: func(payload interface{}) error {
+			*called = "s3"
+			return nil
+		}}}}},
+		cron: map[string]*eventprocessor.CronInvocation{"NIGHTLY": {CronHandlerFunc: func(payload interface{}) (int, interface{}, error) {
+			*called = "cron"
+			return 200, nil, nil
+		}}},
+		lambda: map[string]*eventprocessor.LambdaInvocation{"ECHO": {LambdaHandlerFunc: func(payload interface{}) (int, interface{}, error) {
+			*called = "lambda"
+			return 200, payload, nil
+		}}},
+		eventBridge: map[string]map[string][]*eventprocessor.EventBridge{"orders": {"Order Placed": {{EventBridgeHandler: func(ctx context.Context, event *events.CloudWatchEvent, detail interface{}) error {
+			*called = "eventbridge"
+			return nil
+		}}}}},
+		dynamoDB: map[string]map[string]*eventprocessor.DynamoDBStream{"CUSTOMER": {eventprocessor.DynamoDBInsert: {DynamoDBStreamHandler: func(ctx context.Context, record *events.DynamoDBEventRecord, newImage, oldImage interface{}) error {
+			*called = "dynamodb"
+			return nil
+		}}}},
+	}
+	return eventprocessor.GetHandler(false, func(ctx context.Context, logger *log.Log, event interface{}, eventType eventprocessor.EventType) eventprocessor.EventProcessor {
+		*eventTypes = append(*eventTypes, eventType)
+		return p
+	})
+}
+
+func TestHandleEventDispatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		event     string
+		eventType eventprocessor.EventType
+		called    string
+	}{
+		{"rest api", `{"resource":"/orders","path":"/orders","httpMethod":"GET","headers":{"accept":"application/json"}}`, eventprocessor.EventAPI, "api"},
+		{"http api", `{"version":"2.0","routeKey":"GET /orders","rawPath":"/orders","requestContext":{"domainName":"api.example.com","http":{"method":"GET","path":"/orders"}}}`, eventprocessor.EventHTTPAPI, "api"},
+		{"function url", `{"version":"2.0","rawPath":"/orders","requestContext":{"domainName":"abc.lambda-url.ap-south-1.on.aws","http":{"method":"GET","path":"/orders"}}}`, eventprocessor.EventFunctionURL, "api"},
+		{"alb", `{"httpMethod":"GET","path":"/orders","requestContext":{"elb":{"targetGroupArn":"arn:aws:elasticloadbalancing:ap-south-1:123456789012:targetgroup/orders/1"}}}`, eventprocessor.EventALB, "api"},
+		{"sns", `{"Records":[{"EventSource":"aws:sns","Sns":{"MessageId":"1","TopicArn":"arn:aws:sns:ap-south-1:123456789012:dev_PAYMENT","Message":"{\"event\":\"transaction.failed\"}"}}]}`, eventprocessor.EventSNS, "sns"},
+		{"sqs", `{"Records":[{"eventSource":"aws:sqs","messageId":"1","eventSourceARN":"arn:aws:sqs:ap-south-1:123456789012:dev_ORDERS"}]}`, eventprocessor.EventSQS, "sqs"},
+		{"sqs records", `{"Records":[{"eventSource":"aws:sqs","messageId":"1","eventSourceARN":"arn:aws:sqs:ap-south-1:123456789012:dev_PAYMENTS"}]}`, eventprocessor.EventSQS, "sqs record"},
+		{"s3", `{"Records":[{"eventSource":"aws:s3","eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"bucket"},"object":{"key":"uploads/file.pdf"}}}]}`, eventprocessor.EventS3, "s3"},
+		{"dynamodb", `{"Records":[{"eventSource":"aws:dynamodb","eventName":"INSERT","eventSourceARN":"arn:aws:dynamodb:ap-south-1:123456789012:table/dev_CUSTOMER/stream/2024-01-01T00:00:00.000","dynamodb":{"SequenceNumber":"1"}}]}`, eventprocessor.EventDynamoDB, "dynamodb"},
+		{"cron", `{"isCron":true,"actionName":"NIGHTLY"}`, eventprocessor.EventCRON, "cron"},
+		{"scheduled rule", `{"detail-type":"Scheduled Event","source":"aws.events","resources":["arn:aws:events:ap-south-1:123456789012:rule/dev_NIGHTLY"],"detail":{}}`, eventprocessor.EventCRON, "cron"},
+		{"eventbridge", `{"detail-type":"Order Placed","source":"orders","detail":{}}`, eventprocessor.EventEventBridge, "eventbridge"},
+		{"lambda", `{"isLambdaInvocation":true,"actionName":"ECHO","payload":"hello"}`, eventprocessor.EventLambda, "lambda"},
+		{"lambda json string", strconv.Quote(`{"isLambdaInvocation":true,"actionName":"ECHO","payload":"hello"}`), eventprocessor.EventLambda, "lambda"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			eventTypes, called := make([]eventprocessor.EventType, 0), ""
+			response, err := dispatchHandler(&eventTypes, &called).HandleEvent(context.TODO(), json.RawMessage(test.event))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if called != test.called {
+				t.Fatalf("called the %q handler, expected %q", called, test.called)
+			}
+			for _, eventType := range eventTypes {
+				if eventType != test.eventType {
+					t.Fatalf("dispatched as %v, expected %v", eventType, test.eventType)
+				}
+			}
+			switch test.called {
+			case "api":
+				// each HTTP trigger answers with its own response type
+				if statusCode := reflect.ValueOf(response).FieldByName("StatusCode"); !statusCode.IsValid() || statusCode.Int() != 200 {
+					t.Fatalf("expected a 200 response, got %#v", response)
+				}
+			case "sqs record":
+				if batch, ok := response.(events.SQSEventResponse); !ok || len(batch.BatchItemFailures) != 0 {
+					t.Fatalf("expected a batch response without failures, got %#v", response)
+				}
+			case "lambda":
+				if response != "hello" {
+					t.Fatalf("expected the action response, got %#v", response)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleEventRejectsUnsupportedEvents(t *testing.T) {
+	for _, event := range []string{`{"hello":"world"}`, `[1,2]`, `not json`} {
+		eventTypes, called := make([]eventprocessor.EventType, 0), ""
+		response, err := dispatchHandler(&eventTypes, &called).HandleEvent(context.TODO(), json.RawMessage(event))
+		custErr, ok := err.(*utils.Error)
+		if !ok || custErr.StatusCode != 400 || response != nil || called != "" {
+			t.Fatalf("expected %v to be rejected with a 400, got %#v %v", event, response, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+)
+
+func httpAPIHandler() *eventprocessor.Handler {
+	router := eventprocessor.NewRouter()
+	router.GET("/orders/{id}", eventprocessor.Route(func(request *eventprocessor.Request[eventprocessor.Empty, eventprocessor.Empty, order]) (int, events.APIGatewayProxyResponse, error) {
+		session := eventprocessor.GetCookies(request.Headers)["session"]
+		return 200, events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers:    map[string]string{"Set-Cookie": "seen=" + request.PathParams.ID},
+			Body:       request.PathParams.ID + " " + session,
+		}, nil
+	}))
+	return newHandler(&processor{api: router.MustBuild()})
+}
+
+func httpAPIRequest(routeKey, path string) events.APIGatewayV2HTTPRequest {
+	return events.APIGatewayV2HTTPRequest{
+		Version:  "2.0",
+		RouteKey: routeKey,
+		RawPath:  path,
+		Headers:  map[string]string{"accept": "application/json"},
+		Cookies:  []string{"session=s1"},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey: routeKey,
+			HTTP:     events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet, Path: path},
+		},
+	}
+}
+
+func TestHTTPAPIRouteKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    events.APIGatewayV2HTTPRequest
+		pathParams map[string]string
+	}{
+		{"route key", httpAPIRequest("GET /orders/{id}", "/orders/42"), map[string]string{"id": "42"}},
+		{"default", httpAPIRequest("$default", "/orders/42"), nil},
+		{"greedy proxy", httpAPIRequest("ANY /{proxy+}", "/orders/42"), map[string]string{"proxy": "orders/42"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.request.PathParameters = test.pathParams
+			response, err := httpAPIHandler().HandleHTTPAPIRequest(context.TODO(), test.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStatus(t, response.StatusCode, 200)
+			if response.Body != "42 s1" {
+				t.Fatal("unexpected body", response.Body)
+			}
+			if len(response.Cookies) != 1 || response.Cookies[0] != "seen=42" {
+				t.Fatal("expected the Set-Cookie header as cookies, got", response.Cookies)
+			}
+		})
+	}
+}
+
+func TestHTTPAPIUnknownPath(t *testing.T) {
+	response, err := httpAPIHandler().HandleHTTPAPIRequest(context.TODO(), httpAPIRequest("ANY /{proxy+}", "/customers/42"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 404)
+}
+
+func TestFunctionURLRequest(t *testing.T) {
+	request := events.LambdaFunctionURLRequest{
+		Version: "2.0",
+		RawPath: "/orders/42",
+		Headers: map[string]string{"accept": "application/json"},
+		Cookies: []string{"session=s1"},
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			DomainName: "abc.lambda-url.ap-south-1.on.aws",
+			HTTP:       events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodGet, Path: "/orders/42"},
+		},
+	}
+	response, err := httpAPIHandler().HandleFunctionURLRequest(context.TODO(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 200)
+	if strings.TrimSpace(response.Body) != "42 s1" || len(response.Cookies) != 1 {
+		t.Fatalf("unexpected response %+v", response)
+	}
+}
@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	sdkaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"gobase-lambda/aws"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/eventprocessor/idempotency"
+)
+
+// paymentHandler counts the handler runs, the handler answers with the status set by the test.
+type paymentHandler struct {
+	calls      int
+	statusCode int
+	started    chan struct{}
+	release    chan struct{}
+}
+
+func (p *paymentHandler) handler(store idempotency.Store, required bool) *eventprocessor.Handler {
+	router := eventprocessor.NewRouter()
+	router.POST("/payments", func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+		p.calls++
+		if p.started != nil {
+			close(p.started)
+			<-p.release
+		}
+		return p.statusCode, map[string]int{"call": p.calls}, nil
+	}, idempotency.Middleware(idempotency.Config{Store: store, Required: required}))
+	return newHandler(&processor{api: router.MustBuild()})
+}
+
+func paymentRequest(key, body string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{
+		HTTPMethod: "POST",
+		Resource:   "/payments",
+		Path:       "/payments",
+		Headers:    map[string]string{"Idempotency-Key": key},
+		Body:       body,
+	}
+}
+
+func postPayment(t *testing.T, handler *eventprocessor.Handler, request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	t.Helper()
+	response, err := handler.HandleAPIRequest(context.TODO(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	payments := &paymentHandler{statusCode: 201}
+	handler := payments.handler(idempotency.NewMemoryStore(), false)
+	first := postPayment(t, handler, paymentRequest("key-1", `{"amount":100}`))
+	replayed := postPayment(t, handler, paymentRequest("key-1", `{"amount":100}`))
+	assertStatus(t, replayed.StatusCode, 201)
+	if payments.calls != 1 || replayed.Body != first.Body || replayed.Headers[idempotency.HeaderReplayed] != "true" {
+		t.Fatalf("expected the first response to be replayed, got %v calls and %+v", payments.calls, replayed)
+	}
+	postPayment(t, handler, paymentRequest("key-2", `{"amount":100}`))
+	if payments.calls != 2 {
+		t.Fatal("expected a new key to run the handler")
+	}
+}
+
+func TestIdempotencyFingerprintMismatch(t *testing.T) {
+	payments := &paymentHandler{statusCode: 201}
+	handler := payments.handler(idempotency.NewMemoryStore(), false)
+	postPayment(t, handler, paymentRequest("key-1", `{"amount":100}`))
+	response := postPayment(t, handler, paymentRequest("key-1", `{"amount":200}`))
+	assertStatus(t, response.StatusCode, 422)
+	if payments.calls != 1 {
+		t.Fatal("expected the handler to run once")
+	}
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+	blocked := &paymentHandler{statusCode: 201, started: make(chan struct{}), release: make(chan struct{})}
+	first, duplicate := blocked.handler(store, false), (&paymentHandler{statusCode: 201}).handler(store, false)
+	done := make(chan events.APIGatewayProxyResponse)
+	go func() {
+		response, _ := first.HandleAPIRequest(context.TODO(), paymentRequest("key-1", `{"amount":100}`))
+		done <- response
+	}()
+	<-blocked.started
+	response := postPayment(t, duplicate, paymentRequest("key-1", `{"amount":100}`))
+	close(blocked.release)
+	assertStatus(t, response.StatusCode, 409)
+	assertStatus(t, (<-done).StatusCode, 201)
+	response = postPayment(t, duplicate, paymentRequest("key-1", `{"amount":100}`))
+	if response.Headers[idempotency.HeaderReplayed] != "true" {
+		t.Fatal("expected the completed response to be replayed")
+	}
+}
+
+func TestIdempotencyServerErrorIsNotStored(t *testing.T) {
+	payments := &paymentHandler{statusCode: 503}
+	handler := payments.handler(idempotency.NewMemoryStore(), false)
+	assertStatus(t, postPayment(t, handler, paymentRequest("key-1", `{"amount":100}`)).StatusCode, 503)
+	payments.statusCode = 201
+	assertStatus(t, postPayment(t, handler, paymentRequest("key-1", `{"amount":100}`)).StatusCode, 201)
+	if payments.calls != 2 {
+		t.Fatal("expected the retry to run the handler")
+	}
+}
+
+func TestIdempotencyKeyRequired(t *testing.T) {
+	payments := &paymentHandler{statusCode: 201}
+	handler := payments.handler(idempotency.NewMemoryStore(), true)
+	assertStatus(t, postPayment(t, handler, paymentRequest("", `{"amount":100}`)).StatusCode, 400)
+	if payments.calls != 0 {
+		t.Fatal("expected the handler not to run")
+	}
+}
+
+func TestMemoryStoreConflicts(t *testing.T) {
+	now := time.Now()
+	record := func(fingerprint, status string, lockedUntil, expiresAt time.Time) *idempotency.Record {
+		return &idempotency.Record{Key: "key", Fingerprint: fingerprint, Status: status, LockedUntil: lockedUntil, ExpiresAt: expiresAt}
+	}
+	tests := []struct {
+		name     string
+		current  *idempotency.Record
+		acquired bool
+	}{
+		{"in progress", record("a", idempotency.StatusInProgress, now.Add(time.Minute), now.Add(time.Hour)), false},
+		{"completed", record("a", idempotency.StatusCompleted, now.Add(-time.Minute), now.Add(time.Hour)), false},
+		{"stale in progress", record("a", idempotency.StatusInProgress, now.Add(-time.Second), now.Add(time.Hour)), true},
+		{"expired", record("a", idempotency.StatusCompleted, now.Add(-time.Hour), now.Add(-time.Second)), true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := idempotency.NewMemoryStore()
+			if _, acquired, _ := store.Acquire(test.current); !acquired {
+				t.Fatal("expected the first record to be acquired")
+			}
+			next := record("b", idempotency.StatusInProgress, now.Add(time.Minute), now.Add(time.Hour))
+			current, acquired, err := store.Acquire(next)
+			if err != nil || acquired != test.acquired {
+				t.Fatalf("acquired %v, expected %v : %v", acquired, test.acquired, err)
+			}
+			if !acquired && current.Fingerprint != "a" {
+				t.Fatal("expected the current record", current)
+			}
+			if !acquired && store.Complete(next) == nil {
+				t.Fatal("expected a record of another request not to be completed")
+			}
+		})
+	}
+}
+
+// removedRecordDynamoDb answers like a table where the record blocking the key is deleted between the
+// conditional put and the read.
+func removedRecordDynamoDb(t *testing.T) (*aws.DynamoDb, *int) {
+	t.Helper()
+	puts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		switch r.Header.Get("X-Amz-Target") {
+		case "DynamoDB_20120810.PutItem":
+			puts++
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException","message":"The conditional request failed"}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	awsSession := session.Must(session.NewSession(&sdkaws.Config{
+		Region:      sdkaws.String("ap-south-1"),
+		Endpoint:    sdkaws.String(server.URL),
+		Credentials: credentials.NewStaticCredentials("test", "test", ""),
+		MaxRetries:  sdkaws.Int(0),
+	}))
+	return aws.GetDynamoDbClient(context.TODO(), aws.GetAWSDynamoDbClient(awsSession)), &puts
+}
+
+func TestDynamoDbStoreRecordRemovedConcurrently(t *testing.T) {
+	client, puts := removedRecordDynamoDb(t)
+	store := idempotency.NewDynamoDbStore(context.TODO(), client, "dev_IDEMPOTENCY")
+	record := &idempotency.Record{Key: "key", Fingerprint: "fingerprint", Status: idempotency.StatusInProgress}
+	current, acquired, err := store.Acquire(record)
+	if err != nil {
+		t.Fatal("expected the removed record to be retried, got", err)
+	}
+	if acquired || current.Status != idempotency.StatusInProgress || current.Fingerprint != record.Fingerprint {
+		t.Fatalf("expected the key to be reported in progress, got %+v %v", current, acquired)
+	}
+	if *puts < 2 {
+		t.Fatal("expected the acquire to be retried, puts", *puts)
+	}
+	response, err := (&paymentHandler{statusCode: 201}).handler(store, false).HandleAPIRequest(context.TODO(), paymentRequest("key", `{"amount":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 409)
+}
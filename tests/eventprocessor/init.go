@@ -0,0 +1,25 @@
+package tests
+
+import (
+	"os"
+
+	sdkaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"gobase-lambda/aws"
+	"gobase-lambda/log"
+)
+
+// The eventprocessor tests run the Handler against stub processors, they don't call AWS and need no
+// config file.
+func init() {
+	os.Setenv("AWS_XRAY_SDK_DISABLED", "TRUE")
+	os.Setenv("stage", "dev")
+	awsSession := session.Must(session.NewSession(&sdkaws.Config{
+		Region:      sdkaws.String("ap-south-1"),
+		Credentials: credentials.NewStaticCredentials("test", "test", ""),
+	}))
+	aws.SetDefaultAWSSession(awsSession)
+	logger := log.NewLogger(false, log.DEBUG, nil)
+	log.SetDefaultLogger(logger)
+}
@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gobase-lambda/eventprocessor"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "orders-api"
+)
+
+var (
+	testRSAKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	testECKey, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+)
+
+func jwk(kid string, key crypto.PublicKey) map[string]string {
+	encode := base64.RawURLEncoding.EncodeToString
+	switch publicKey := key.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kid": kid, "kty": "RSA", "use": "sig", "n": encode(publicKey.N.Bytes()), "e": encode(big.NewInt(int64(publicKey.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kid": kid, "kty": "EC", "crv": "P-256", "x": encode(publicKey.X.FillBytes(make([]byte, 32))), "y": encode(publicKey.Y.FillBytes(make([]byte, 32)))}
+	}
+	return nil
+}
+
+func jwksBlob(keys ...map[string]string) []byte {
+	blob, _ := json.Marshal(map[string]interface{}{"keys": keys})
+	return blob
+}
+
+func loadTestJWKS(t *testing.T) *eventprocessor.JWKS {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksBlob(jwk("rsa", &testRSAKey.PublicKey), jwk("ec", &testECKey.PublicKey)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jwks, err := eventprocessor.LoadJWKSFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jwks
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{"sub": "user-1", "iss": testIssuer, "aud": testAudience, "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+// signToken signs the claims with signAlg and puts alg in the header, so that they can differ. The
+// token isn't signed for an unknown signAlg.
+func signToken(t *testing.T, alg, signAlg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	var err error
+	switch signAlg {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, testRSAKey, crypto.SHA256, digest[:])
+	case "PS256":
+		signature, err = rsa.SignPSS(rand.Reader, testRSAKey, crypto.SHA256, digest[:], nil)
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, testECKey, digest[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func withClaims(changes map[string]interface{}) map[string]interface{} {
+	claims := validClaims()
+	for key, value := range changes {
+		if value == nil {
+			delete(claims, key)
+			continue
+		}
+		claims[key] = value
+	}
+	return claims
+}
+
+func TestJWTVerify(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		token   func(t *testing.T) string
+		leeway  time.Duration
+		wantErr string
+	}{
+		{"RS256", func(t *testing.T) string { return signToken(t, "RS256", "RS256", "rsa", validClaims()) }, 0, ""},
+		{"PS256", func(t *testing.T) string { return signToken(t, "PS256", "PS256", "rsa", validClaims()) }, 0, ""},
+		{"ES256", func(t *testing.T) string { return signToken(t, "ES256", "ES256", "ec", validClaims()) }, 0, ""},
+		{"alg none", func(t *testing.T) string { return signToken(t, "none", "none", "rsa", validClaims()) }, 0, "unsupported algorithm"},
+		{"HS256 with an RSA key", func(t *testing.T) string { return signToken(t, "HS256", "RS256", "rsa", validClaims()) }, 0, "unsupported algorithm"},
+		{"ES256 with an RSA key", func(t *testing.T) string { return signToken(t, "ES256", "RS256", "rsa", validClaims()) }, 0, "unsupported algorithm"},
+		{"RS256 with an EC key", func(t *testing.T) string { return signToken(t, "RS256", "ES256", "ec", validClaims()) }, 0, "unsupported algorithm"},
+		{"RS256 signed with PSS", func(t *testing.T) string { return signToken(t, "RS256", "PS256", "rsa", validClaims()) }, 0, "verification error"},
+		{"tampered payload", func(t *testing.T) string {
+			parts := strings.Split(signToken(t, "RS256", "RS256", "rsa", validClaims()), ".")
+			payload, _ := json.Marshal(withClaims(map[string]interface{}{"sub": "admin"}))
+			return parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+		}, 0, "verification error"},
+		{"tampered signature", func(t *testing.T) string {
+			parts := strings.Split(signToken(t, "ES256", "ES256", "ec", validClaims()), ".")
+			signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+			signature[10] ^= 0xff
+			return parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(signature)
+		}, 0, "invalid signature"},
+		{"unknown kid", func(t *testing.T) string { return signToken(t, "RS256", "RS256", "rotated", validClaims()) }, 0, "unknown key id"},
+		{"malformed", func(t *testing.T) string { return "token" }, 0, "malformed token"},
+		{"expired", func(t *testing.T) string {
+			return signToken(t, "RS256", "RS256", "rsa", withClaims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()}))
+		}, 0, "token is expired"},
+		{"expired within the leeway", func(t *testing.T) string {
+			return signToken(t, "RS256", "RS256", "rsa", withClaims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()}))
+		}, 2 * time.Minute, ""},
+		{"without exp", func(t *testing.T) string {
+			return signToken(t, "RS256", "RS256", "rsa", withClaims(map[string]interface{}{"exp": nil}))
+		}, 0, "token is expired"},
+		{"not valid yet", func(t *testing.T) string {
+			return signToken(t, "RS256", "RS256", "rsa", withClaims(map[string]interface{}{"nbf": now.Add(time.Minute).Unix()}))
+		}, 0, "token is not valid yet"},
+		{"not valid yet within the leeway", func(t *testing.T) string {
+			return signToken(t, "RS256", "RS256", "rsa", withClaims(map[string]interface{}{"nbf": now.Add(time.Minute).Unix()}))
+		}, 2 * time.Minute, ""},
+		{"issuer mismatch", func(t *testing.T) string {
+			return signToken(t, "RS256", "RS256", "rsa", withClaims(map[string]interface{}{"iss": "https://evil.example.com"}))
+		}, 0, "invalid issuer"},
+		{"audience mismatch", func(t *testing.T) string {
+			return signToken(t, "RS256", "RS256", "rsa", withClaims(map[string]interface{}{"aud": []string{"payments-api"}}))
+		}, 0, "invalid audience"},
+		{"cognito client_id audience", func(t *testing.T) string {
+			return signToken(t, "RS256", "RS256", "rsa", withClaims(map[string]interface{}{"aud": nil, "client_id": testAudience}))
+		}, 0, ""},
+	}
+	jwks := loadTestJWKS(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verifier := &eventprocessor.JWTVerifier{JWKS: jwks, Issuer: testIssuer, Audience: []string{testAudience}, Leeway: test.leeway}
+			claims, err := verifier.Verify(test.token(t))
+			if test.wantErr == "" {
+				if err != nil || claims["sub"] != "user-1" {
+					t.Fatal("expected the token to be valid, got", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("expected %q, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestJWKSRefreshForUnknownKeyId(t *testing.T) {
+	var mutex sync.Mutex
+	keys, fetches := jwksBlob(jwk("rsa", &testRSAKey.PublicKey)), 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		fetches++
+		w.Write(keys)
+	}))
+	defer server.Close()
+	jwks, err := eventprocessor.LoadJWKSFromURL(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := &eventprocessor.JWTVerifier{JWKS: jwks, Issuer: testIssuer, Audience: []string{testAudience}}
+	mutex.Lock()
+	keys = jwksBlob(jwk("rsa", &testRSAKey.PublicKey), jwk("rotated", &testECKey.PublicKey))
+	mutex.Unlock()
+	token := signToken(t, "ES256", "ES256", "rotated", validClaims())
+	if _, err = verifier.Verify(token); err == nil || fetches != 1 {
+		t.Fatalf("expected the unknown key to be rejected without a refresh, got %v after %v fetches", err, fetches)
+	}
+	jwks.RefreshInterval = time.Nanosecond
+	if _, err = verifier.Verify(token); err != nil || fetches != 2 {
+		t.Fatalf("expected the rotated key to be fetched, got %v after %v fetches", err, fetches)
+	}
+}
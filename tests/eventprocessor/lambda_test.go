@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda/messages"
+	"gobase-lambda/eventprocessor"
+)
+
+func echoAction(payload interface{}) (int, interface{}, error) {
+	return 200, payload, nil
+}
+
+func assertInvokeError(t *testing.T, err error, errorType string) {
+	t.Helper()
+	invokeErr, ok := err.(messages.InvokeResponse_Error)
+	if !ok || invokeErr.Type != errorType {
+		t.Fatalf("expected a %v invoke error, got %#v", errorType, err)
+	}
+}
+
+func TestLambdaInvocation(t *testing.T) {
+	handler := newHandler(&processor{lambda: map[string]*eventprocessor.LambdaInvocation{"ECHO": {LambdaHandlerFunc: echoAction}}})
+	tests := []struct {
+		name    string
+		request interface{}
+	}{
+		{"struct", eventprocessor.LambdaEvent{IsLambdaInvocation: true, ActionName: "ECHO", Payload: map[string]interface{}{"hello": "world"}}},
+		{"json string", `{"isLambdaInvocation":true,"actionName":"ECHO","payload":{"hello":"world"}}`},
+		{"map", map[string]interface{}{"isLambdaInvocation": true, "actionName": "ECHO", "payload": map[string]interface{}{"hello": "world"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := handler.HandleLambdaInvocation(context.TODO(), test.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(response, map[string]interface{}{"hello": "world"}) {
+				t.Fatalf("unexpected response %#v", response)
+			}
+		})
+	}
+}
+
+func TestLambdaInvocationRequiresTheFlag(t *testing.T) {
+	called := false
+	handler := newHandler(&processor{lambda: map[string]*eventprocessor.LambdaInvocation{"ECHO": {LambdaHandlerFunc: func(payload interface{}) (int, interface{}, error) {
+		called = true
+		return 200, payload, nil
+	}}}})
+	response, err := handler.HandleLambdaInvocation(context.TODO(), `{"actionName":"ECHO","payload":{}}`)
+	assertInvokeError(t, err, "BAD_REQUEST")
+	if response != nil || called {
+		t.Fatal("handler ran for an event without isLambdaInvocation")
+	}
+}
+
+func TestLambdaInvocationErrors(t *testing.T) {
+	handler := newHandler(&processor{lambda: map[string]*eventprocessor.LambdaInvocation{
+		"FAIL":   {LambdaHandlerFunc: func(payload interface{}) (int, interface{}, error) { return 500, nil, errHandler }},
+		"REJECT": {LambdaHandlerFunc: func(payload interface{}) (int, interface{}, error) { return 409, "conflict", nil }},
+	}})
+	tests := []struct {
+		name      string
+		request   string
+		errorType string
+		response  interface{}
+	}{
+		{"unmapped action", `{"isLambdaInvocation":true,"actionName":"UNKNOWN"}`, "NOT_FOUND", nil},
+		{"invalid json", `{"isLambdaInvocation":true,`, "BAD_REQUEST", nil},
+		{"handler error", `{"isLambdaInvocation":true,"actionName":"FAIL"}`, "INTERNAL_SERVER_ERROR", nil},
+		{"error status", `{"isLambdaInvocation":true,"actionName":"REJECT"}`, "ACTION_FAILED", "conflict"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := handler.HandleLambdaInvocation(context.TODO(), test.request)
+			assertInvokeError(t, err, test.errorType)
+			if !reflect.DeepEqual(response, test.response) {
+				t.Fatalf("response %#v, expected %#v", response, test.response)
+			}
+		})
+	}
+}
+
+func TestLambdaInvocationRunsThroughTheMiddleware(t *testing.T) {
+	var eventType eventprocessor.EventType
+	handler := newHandler(&processor{lambda: map[string]*eventprocessor.LambdaInvocation{"ECHO": {
+		LambdaContextHandlerFunc: func(ctx context.Context, payload interface{}) (int, interface{}, error) {
+			if eventprocessor.GetLogger(ctx) == nil {
+				t.Error("the ctx has no logger")
+			}
+			return 200, payload, nil
+		},
+	}}})
+	handler.UseFor(eventprocessor.EventLambda, eventprocessor.MiddlewareFunc(func(invocation *eventprocessor.Invocation, next eventprocessor.Next) (interface{}, error) {
+		eventType = invocation.EventType
+		return next(invocation)
+	}))
+	response, err := handler.HandleLambdaInvocation(context.TODO(), `{"isLambdaInvocation":true,"actionName":"ECHO","payload":"hello"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != "hello" || eventType != eventprocessor.EventLambda {
+		t.Fatalf("unexpected response %#v for event type %v", response, eventType)
+	}
+}
+
+func TestLambdaInvocationPanicAndTimeout(t *testing.T) {
+	handler := newHandler(&processor{lambda: map[string]*eventprocessor.LambdaInvocation{
+		"PANIC": {LambdaHandlerFunc: func(payload interface{}) (int, interface{}, error) { panic("action panic") }},
+		"SLOW": {LambdaContextHandlerFunc: func(ctx context.Context, payload interface{}) (int, interface{}, error) {
+			<-ctx.Done()
+			return 200, nil, nil
+		}},
+	}})
+	handler.SetTimeoutMargin(500 * time.Millisecond)
+	_, err := handler.HandleLambdaInvocation(context.TODO(), `{"isLambdaInvocation":true,"actionName":"PANIC"}`)
+	assertInvokeError(t, err, "INTERNAL_SERVER_ERROR")
+	_, err = handler.HandleLambdaInvocation(timeoutContext(t), `{"isLambdaInvocation":true,"actionName":"SLOW"}`)
+	assertInvokeError(t, err, "TIMEOUT")
+}
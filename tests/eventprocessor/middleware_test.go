@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+)
+
+// recordMiddleware appends the name to invoked before calling next.
+func recordMiddleware(invoked *[]string, name string) eventprocessor.Middleware {
+	return eventprocessor.MiddlewareFunc(func(invocation *eventprocessor.Invocation, next eventprocessor.Next) (interface{}, error) {
+		*invoked = append(*invoked, name)
+		return next(invocation)
+	})
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	invoked := make([]string, 0)
+	router := eventprocessor.NewRouter()
+	router.Use(recordMiddleware(&invoked, "router"))
+	group := router.Group("/orders", recordMiddleware(&invoked, "group"))
+	group.GET("/{id}", func(ctx context.Context, headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+		invoked = append(invoked, "handler")
+		return 200, pathParam, nil
+	}, eventprocessor.MiddlewareFunc(func(invocation *eventprocessor.Invocation, next eventprocessor.Next) (interface{}, error) {
+		if invocation.API == nil || invocation.API.Resource != "/orders/{id}" {
+			t.Fatal("route middleware called without the route", invocation.API)
+		}
+		invoked = append(invoked, "route")
+		return next(invocation)
+	}))
+	handler := newHandler(&processor{api: router.MustBuild()})
+	handler.UseFor(eventprocessor.EventAPI, recordMiddleware(&invoked, "api"))
+	handler.UseFor(eventprocessor.EventSNS, recordMiddleware(&invoked, "sns"))
+	handler.Use(recordMiddleware(&invoked, "global"))
+	request := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Resource: "/orders/{id}", Path: "/orders/1", PathParameters: map[string]string{"id": "1"}}
+	response, err := handler.HandleAPIRequest(context.TODO(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 200)
+	expected := []string{"global", "api", "router", "group", "route", "handler"}
+	if !reflect.DeepEqual(invoked, expected) {
+		t.Fatalf("middlewares invoked %v, expected %v", invoked, expected)
+	}
+}
+
+func TestMiddlewareCanShortCircuit(t *testing.T) {
+	called := false
+	router := eventprocessor.NewRouter()
+	router.GET("/orders", func(ctx context.Context, headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+		called = true
+		return 200, nil, nil
+	})
+	handler := newHandler(&processor{api: router.MustBuild()})
+	handler.Use(eventprocessor.MiddlewareFunc(func(invocation *eventprocessor.Invocation, next eventprocessor.Next) (interface{}, error) {
+		return events.APIGatewayProxyResponse{StatusCode: 429, Body: "slow down"}, nil
+	}))
+	response, err := handler.HandleAPIRequest(context.TODO(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Resource: "/orders", Path: "/orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 429)
+	if called || response.Body != "slow down" {
+		t.Fatal("expected the middleware response, handler called", called)
+	}
+}
+
+func TestMiddlewareForTriggers(t *testing.T) {
+	invoked := make([]string, 0)
+	handler := newHandler(&processor{
+		sns: map[string]map[string]*eventprocessor.SNS{"dev_PAYMENT": {"transaction.failed": {SnsHandler: func(payload interface{}) error {
+			invoked = append(invoked, "sns handler")
+			return nil
+		}}}},
+		sqs: map[string]*eventprocessor.SQS{"ORDERS": {SQSHandler: func(payload interface{}) error {
+			invoked = append(invoked, "sqs handler")
+			return nil
+		}}},
+	})
+	handler.Use(eventprocessor.MiddlewareFunc(func(invocation *eventprocessor.Invocation, next eventprocessor.Next) (interface{}, error) {
+		invoked = append(invoked, string(invocation.EventType))
+		return next(invocation)
+	}))
+	handler.UseFor(eventprocessor.EventSQS, recordMiddleware(&invoked, "sqs"))
+	_, err := handler.HandleSNSRequest(context.TODO(), events.SNSEvent{Records: []events.SNSEventRecord{
+		snsRecord("1", "dev_PAYMENT", `{"event":"transaction.failed"}`),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = handler.HandleSQSRequest(context.TODO(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "1", Body: `{}`, EventSourceARN: "arn:aws:sqs:ap-south-1:123456789012:dev_ORDERS"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"SNS", "sns handler", "SQS", "sqs", "sqs handler"}
+	if !reflect.DeepEqual(invoked, expected) {
+		t.Fatalf("middlewares invoked %v, expected %v", invoked, expected)
+	}
+}
+
+func TestMiddlewarePanicIsRecovered(t *testing.T) {
+	handler := newHandler(&processor{cron: map[string]*eventprocessor.CronInvocation{
+		"ACTION_1": {CronHandlerFunc: func(payload interface{}) (int, interface{}, error) {
+			t.Fatal("handler called after the middleware panic")
+			return 200, nil, nil
+		}},
+	}})
+	handler.UseFor(eventprocessor.EventCRON, eventprocessor.MiddlewareFunc(func(invocation *eventprocessor.Invocation, next eventprocessor.Next) (interface{}, error) {
+		panic("unexpected failure")
+	}))
+	response, err := handler.HandleCronInvocation(context.TODO(), eventprocessor.CronEvent{IsCron: true, ActionName: "ACTION_1"})
+	if err == nil {
+		t.Fatal("expected the panic to be returned")
+	}
+	assertStatus(t, response.StatusCode, 500)
+}
+
+func TestSetBuiltinMiddleware(t *testing.T) {
+	router := eventprocessor.NewRouter()
+	router.GET("/orders", func(ctx context.Context, headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+		panic("unexpected failure")
+	})
+	handler := newHandler(&processor{api: router.MustBuild()})
+	invoked := make([]string, 0)
+	statusCodes := make([]int, 0)
+	metrics := eventprocessor.MiddlewareFunc(func(invocation *eventprocessor.Invocation, next eventprocessor.Next) (interface{}, error) {
+		result, err := next(invocation)
+		if err != nil {
+			t.Fatal("expected the error to be mapped inside the metrics middleware", err)
+		}
+		statusCodes = append(statusCodes, result.(events.APIGatewayProxyResponse).StatusCode)
+		return result, err
+	})
+	handler.SetBuiltinMiddleware(metrics, eventprocessor.ErrorMappingMiddleware, eventprocessor.RecoveryMiddleware)
+	handler.Use(recordMiddleware(&invoked, "global"))
+	response, err := handler.HandleAPIRequest(context.TODO(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Resource: "/orders", Path: "/orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 500)
+	if !reflect.DeepEqual(statusCodes, []int{500}) || !reflect.DeepEqual(invoked, []string{"global"}) {
+		t.Fatal("unexpected middlewares invoked", statusCodes, invoked)
+	}
+}
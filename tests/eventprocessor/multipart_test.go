@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+)
+
+type upload struct {
+	FileName string                   `json:"fileName"`
+	File     *eventprocessor.FilePart `json:"file" validate:"required"`
+}
+
+type uploadRequest = eventprocessor.Request[upload, eventprocessor.Empty, eventprocessor.Empty]
+
+func uploadHandler(called *bool) *eventprocessor.Handler {
+	router := eventprocessor.NewRouter()
+	router.POST("/upload", eventprocessor.Route(func(request *uploadRequest) (int, *order, error) {
+		*called = true
+		return 201, &order{ID: request.Body.File.FileName}, nil
+	})).Multipart = &eventprocessor.MultipartLimits{MaxFileSize: 16, MaxFiles: 1, AllowedTypes: []string{"application/pdf"}}
+	return newHandler(&processor{api: router.MustBuild()})
+}
+
+type formFile struct {
+	field, name string
+	content     []byte
+}
+
+// multipartBody writes the values and files as a multipart/form-data body and returns it with its
+// content type.
+func multipartBody(values map[string]string, files ...formFile) (string, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for key, value := range values {
+		writer.WriteField(key, value)
+	}
+	for _, file := range files {
+		fileWriter, _ := writer.CreateFormFile(file.field, file.name)
+		fileWriter.Write(file.content)
+	}
+	writer.Close()
+	return body.String(), writer.FormDataContentType()
+}
+
+func TestFilePartIsNotBoundFromJSON(t *testing.T) {
+	called := false
+	handler := uploadHandler(&called)
+	for _, contentType := range []string{"application/json", "application/msgpack", ""} {
+		t.Run(contentType, func(t *testing.T) {
+			request := events.APIGatewayProxyRequest{
+				HTTPMethod: "POST",
+				Resource:   "/upload",
+				Path:       "/upload",
+				Headers:    map[string]string{"Content-Type": contentType},
+				Body:       `{"fileName":"statement.pdf","file":{"fileName":"statement.exe","size":1,"contentType":"application/pdf"}}`,
+			}
+			response, err := handler.HandleAPIRequest(context.TODO(), request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStatus(t, response.StatusCode, 415)
+			if called {
+				t.Fatal("handler ran with a file which was not checked")
+			}
+		})
+	}
+	if err := json.Unmarshal([]byte(`{"fileName":"statement.pdf"}`), &eventprocessor.FilePart{}); err == nil {
+		t.Fatal("expected FilePart to reject JSON")
+	}
+}
+
+func TestFilePartIsBoundFromMultipart(t *testing.T) {
+	called := false
+	handler := uploadHandler(&called)
+	body, contentType := multipartBody(map[string]string{"fileName": "statement.pdf"}, formFile{"file", "statement.pdf", []byte("%PDF-1.4")})
+	request := events.APIGatewayProxyRequest{
+		HTTPMethod: "POST",
+		Resource:   "/upload",
+		Path:       "/upload",
+		Headers:    map[string]string{"Content-Type": contentType},
+		Body:       body,
+	}
+	response, err := handler.HandleAPIRequest(context.TODO(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 201)
+	if !called {
+		t.Fatal("handler didn't run")
+	}
+}
+
+func TestMultipartFormValuesAreValidatedOnBuild(t *testing.T) {
+	type invalidUpload struct {
+		Tags map[string]string        `json:"tags"`
+		File *eventprocessor.FilePart `json:"file"`
+	}
+	router := eventprocessor.NewRouter()
+	router.POST("/upload", eventprocessor.Route(func(request *eventprocessor.Request[invalidUpload, eventprocessor.Empty, eventprocessor.Empty]) (int, *order, error) {
+		return 201, &order{}, nil
+	}))
+	if _, err := router.Build(); err == nil || !strings.Contains(err.Error(), "unsupported form value type") {
+		t.Fatal("expected the map field to be rejected, got", err)
+	}
+	router = eventprocessor.NewRouter()
+	router.POST("/upload", eventprocessor.Route(func(request *eventprocessor.Request[eventprocessor.MultipartForm, eventprocessor.Empty, eventprocessor.Empty]) (int, *order, error) {
+		return 201, &order{}, nil
+	}))
+	if _, err := router.Build(); err != nil {
+		t.Fatal("expected a MultipartForm body to be accepted, got", err)
+	}
+}
+
+func TestMultipartLimitsAreEnforced(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      []formFile
+		statusCode int
+		errorCode  string
+	}{
+		{"file too large", []formFile{{"file", "statement.pdf", []byte("%PDF-1.4 with a long body")}}, 413, "FILE_TOO_LARGE"},
+		{"file type not allowed", []formFile{{"file", "statement.pdf", []byte("\x89PNG\r\n\x1a\n")}}, 415, "UNSUPPORTED_FILE_TYPE"},
+		{"too many files", []formFile{{"file", "a.pdf", []byte("%PDF-1.4")}, {"file", "b.pdf", []byte("%PDF-1.4")}}, 400, "BAD_REQUEST"},
+		{"file missing", nil, 400, "VALIDATION_FAILED"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			called := false
+			body, contentType := multipartBody(map[string]string{"fileName": "statement.pdf"}, test.files...)
+			request := events.APIGatewayProxyRequest{
+				HTTPMethod: "POST",
+				Resource:   "/upload",
+				Path:       "/upload",
+				Headers:    map[string]string{"Content-Type": contentType},
+				Body:       body,
+			}
+			response, err := uploadHandler(&called).HandleAPIRequest(context.TODO(), request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStatus(t, response.StatusCode, test.statusCode)
+			if !strings.Contains(response.Body, test.errorCode) {
+				t.Fatalf("body %v, expected the %v error", response.Body, test.errorCode)
+			}
+			if called {
+				t.Fatal("handler ran with a rejected upload")
+			}
+		})
+	}
+}
+
+func TestMultipartFormBody(t *testing.T) {
+	var form *eventprocessor.MultipartForm
+	router := eventprocessor.NewRouter()
+	router.POST("/documents", eventprocessor.Route(func(request *eventprocessor.Request[eventprocessor.MultipartForm, eventprocessor.Empty, eventprocessor.Empty]) (int, *order, error) {
+		form = request.Body
+		return 201, &order{}, nil
+	})).Multipart = &eventprocessor.MultipartLimits{AllowedTypes: []string{"image/*", "application/pdf"}}
+	handler := newHandler(&processor{api: router.MustBuild()})
+	body, contentType := multipartBody(map[string]string{"documentType": "KYC"},
+		formFile{"pages", "front.png", []byte("\x89PNG\r\n\x1a\n")},
+		formFile{"pages", "back.pdf", []byte("%PDF-1.4")},
+	)
+	request := events.APIGatewayProxyRequest{
+		HTTPMethod:      "POST",
+		Resource:        "/documents",
+		Path:            "/documents",
+		Headers:         map[string]string{"Content-Type": contentType},
+		Body:            base64.StdEncoding.EncodeToString([]byte(body)),
+		IsBase64Encoded: true,
+	}
+	response, err := handler.HandleAPIRequest(context.TODO(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 201)
+	pages := form.Files["pages"]
+	switch {
+	case form.Value("documentType") != "KYC":
+		t.Fatal("unexpected form values", form.Values)
+	case len(pages) != 2:
+		t.Fatal("unexpected files", form.Files)
+	case pages[0].FileName != "front.png" || pages[0].ContentType != "image/png" || pages[0].DeclaredContentType != "application/octet-stream":
+		t.Fatalf("unexpected first file %+v", pages[0])
+	case pages[1].ContentType != "application/pdf" || string(pages[1].Bytes()) != "%PDF-1.4" || pages[1].Size != 8:
+		t.Fatalf("unexpected second file %+v", pages[1])
+	}
+}
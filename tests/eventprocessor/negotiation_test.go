@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+)
+
+const browserAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+type order struct {
+	ID string `json:"id" xml:"id"`
+}
+
+type orderRequest = eventprocessor.Request[eventprocessor.Empty, eventprocessor.Empty, eventprocessor.Empty]
+
+func negotiationRequest(resource, accept string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{
+		HTTPMethod: "GET",
+		Resource:   resource,
+		Path:       resource,
+		Headers:    map[string]string{"Accept": accept},
+	}
+}
+
+func assertContentType(t *testing.T, response events.APIGatewayProxyResponse, expected string) {
+	t.Helper()
+	if response.Headers["Content-Type"] != expected {
+		t.Fatalf("content type %v, expected %v : %v", response.Headers["Content-Type"], expected, response.Body)
+	}
+}
+
+func TestBrowserAcceptEncodesMapsAsJSON(t *testing.T) {
+	router := eventprocessor.NewRouter()
+	router.GET("/orders", func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+		return 200, map[string]string{"id": "1"}, nil
+	})
+	handler := newHandler(&processor{api: router.MustBuild()})
+	response, err := handler.HandleAPIRequest(context.TODO(), negotiationRequest("/orders", browserAccept))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 200)
+	assertContentType(t, response, eventprocessor.MediaTypeJSON)
+	if response.Body != "{\"id\":\"1\"}\n" {
+		t.Fatal("unexpected body", response.Body)
+	}
+}
+
+func TestNegotiationPrefersTheDefaultOnTies(t *testing.T) {
+	router := eventprocessor.NewRouter()
+	router.GET("/orders", eventprocessor.Route(func(request *orderRequest) (int, *order, error) {
+		return 200, &order{ID: "1"}, nil
+	}))
+	handler := newHandler(&processor{api: router.MustBuild()})
+	tests := []struct {
+		accept      string
+		contentType string
+	}{
+		{"", eventprocessor.MediaTypeJSON},
+		{"*/*", eventprocessor.MediaTypeJSON},
+		{"application/xml, */*", eventprocessor.MediaTypeJSON},
+		{"application/xml, application/*", eventprocessor.MediaTypeJSON},
+		{"application/xml, */*;q=0.5", eventprocessor.MediaTypeXML},
+		{"application/xml", eventprocessor.MediaTypeXML},
+		{"text/*", eventprocessor.MediaTypeCSV},
+		{"application/json;q=0, application/*", eventprocessor.MediaTypeMsgpack},
+	}
+	for _, test := range tests {
+		t.Run(test.accept, func(t *testing.T) {
+			response, err := handler.HandleAPIRequest(context.TODO(), negotiationRequest("/orders", test.accept))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStatus(t, response.StatusCode, 200)
+			assertContentType(t, response, test.contentType)
+		})
+	}
+}
+
+func TestNotAcceptableIsRejectedBeforeTheHandler(t *testing.T) {
+	called := false
+	router := eventprocessor.NewRouter()
+	router.POST("/orders", eventprocessor.Route(func(request *orderRequest) (int, *order, error) {
+		called = true
+		return 201, &order{ID: "1"}, nil
+	}))
+	handler := newHandler(&processor{api: router.MustBuild()})
+	request := negotiationRequest("/orders", "image/png")
+	request.HTTPMethod = "POST"
+	response, err := handler.HandleAPIRequest(context.TODO(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 406)
+	if called {
+		t.Fatal("handler ran for a request it can't answer")
+	}
+}
+
+func TestFileResponseIgnoresNegotiation(t *testing.T) {
+	router := eventprocessor.NewRouter()
+	router.GET("/report", eventprocessor.Route(func(request *orderRequest) (int, *eventprocessor.FileResponse, error) {
+		return 200, &eventprocessor.FileResponse{Body: []byte("%PDF"), ContentType: "application/pdf"}, nil
+	}))
+	handler := newHandler(&processor{api: router.MustBuild()})
+	response, err := handler.HandleAPIRequest(context.TODO(), negotiationRequest("/report", "application/pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 200)
+	assertContentType(t, response, "application/pdf")
+}
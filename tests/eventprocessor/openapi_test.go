@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"gobase-lambda/eventprocessor"
+)
+
+type reportQuery struct {
+	Window time.Duration `json:"window"`
+	Since  time.Time     `json:"since"`
+}
+
+type reportRequest = eventprocessor.Request[eventprocessor.Empty, reportQuery, eventprocessor.Empty]
+
+func TestOpenAPIDurationIsAString(t *testing.T) {
+	router := eventprocessor.NewRouter()
+	router.GET("/reports", eventprocessor.Route(func(request *reportRequest) (int, *order, error) {
+		return 200, &order{}, nil
+	}))
+	document := eventprocessor.GenerateOpenAPI(router.MustBuild(), eventprocessor.OpenAPIInfo{Title: "reports", Version: "1.0.0"})
+	schemas := make(map[string]*eventprocessor.OpenAPISchema)
+	for _, parameter := range document.Paths["/reports"]["get"].Parameters {
+		schemas[parameter.Name] = parameter.Schema
+	}
+	if window := schemas["window"]; window == nil || window.Type != "string" || len(window.Examples) == 0 {
+		t.Fatalf("expected a string schema with an example for durations, got %+v", window)
+	}
+	if since := schemas["since"]; since == nil || since.Format != "date-time" {
+		t.Fatalf("expected a date-time schema for times, got %+v", since)
+	}
+}
+
+func pathSchemas(document *eventprocessor.OpenAPIDocument, resource string) map[string]*eventprocessor.OpenAPISchema {
+	schemas := make(map[string]*eventprocessor.OpenAPISchema)
+	for _, parameter := range document.Paths[resource]["get"].Parameters {
+		if parameter.In == "path" {
+			schemas[parameter.Name] = parameter.Schema
+		}
+	}
+	return schemas
+}
+
+func TestOpenAPIPathParamsMatchTheBinding(t *testing.T) {
+	router := eventprocessor.NewRouter()
+	router.GET("/orders/{id}/{ref}", eventprocessor.Route(func(request *eventprocessor.Request[eventprocessor.Empty, eventprocessor.Empty, orderPath]) (int, *orderPath, error) {
+		return 200, request.PathParams, nil
+	}))
+	document := eventprocessor.GenerateOpenAPI(router.MustBuild(), eventprocessor.OpenAPIInfo{Title: "orders", Version: "1.0.0"})
+	schemas := pathSchemas(document, "/orders/{id}/{ref}")
+	if id := schemas["id"]; id == nil || id.Type != "integer" {
+		t.Fatalf("expected an integer schema for the int path param, got %+v", id)
+	}
+	if ref := schemas["ref"]; ref == nil || ref.Type != "string" {
+		t.Fatalf("expected a string schema for the uuid path param, got %+v", ref)
+	}
+	// a field the binder can't set is documented as the raw string value
+	apiMap := map[string]map[string]*eventprocessor.API{"/orders/{id}": {"GET": {
+		PathParams: &struct {
+			ID []int `json:"id"`
+		}{},
+		ApiHandler: func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+			return 200, nil, nil
+		},
+	}}}
+	document = eventprocessor.GenerateOpenAPI(apiMap, eventprocessor.OpenAPIInfo{Title: "orders", Version: "1.0.0"})
+	if id := pathSchemas(document, "/orders/{id}")["id"]; id == nil || id.Type != "string" {
+		t.Fatalf("expected a string schema for the unsupported path param, got %+v", id)
+	}
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+)
+
+func TestGetPrincipal(t *testing.T) {
+	tests := []struct {
+		name       string
+		authorizer map[string]interface{}
+		expected   eventprocessor.Principal
+	}{
+		{
+			"cognito user pool",
+			map[string]interface{}{"claims": map[string]interface{}{
+				"iss":              "https://cognito-idp.ap-south-1.amazonaws.com/ap-south-1_abc",
+				"sub":              "user-1",
+				"email":            "user@example.com",
+				"cognito:username": "user1",
+				"cognito:groups":   "[admin ops]",
+				"scope":            "orders/read orders/write",
+			}},
+			eventprocessor.Principal{Source: eventprocessor.PrincipalSourceCognito, Subject: "user-1", Username: "user1", Email: "user@example.com", Scopes: []string{"orders/read", "orders/write"}, Groups: []string{"admin", "ops"}},
+		},
+		{
+			"jwt authorizer scopes",
+			map[string]interface{}{"claims": map[string]string{"iss": "https://auth.example.com", "sub": "client-1"}, "scopes": []string{"orders/read"}},
+			eventprocessor.Principal{Source: eventprocessor.PrincipalSourceJWT, Subject: "client-1", Scopes: []string{"orders/read"}, Groups: []string{}},
+		},
+		{
+			"lambda authorizer",
+			map[string]interface{}{"principalId": "user-2", "username": "user2", "scopes": "orders/read,orders/write", "groups": "ops"},
+			eventprocessor.Principal{Source: eventprocessor.PrincipalSourceLambda, Subject: "user-2", Username: "user2", Scopes: []string{"orders/read", "orders/write"}, Groups: []string{"ops"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := events.APIGatewayProxyRequest{RequestContext: events.APIGatewayProxyRequestContext{Authorizer: test.authorizer}}
+			principal := eventprocessor.GetPrincipal(&request)
+			if principal == nil {
+				t.Fatal("expected a principal")
+			}
+			principal.Claims = nil
+			if !reflect.DeepEqual(*principal, test.expected) {
+				t.Fatalf("principal %+v, expected %+v", *principal, test.expected)
+			}
+		})
+	}
+	if eventprocessor.GetPrincipal(&events.APIGatewayProxyRequest{}) != nil {
+		t.Fatal("expected no principal without an authorizer")
+	}
+}
+
+func principalHandler() *eventprocessor.Handler {
+	router := eventprocessor.NewRouter()
+	whoami := eventprocessor.Route(func(request *orderRequest) (int, map[string]string, error) {
+		if request.Principal == nil {
+			return 200, map[string]string{"username": "anonymous"}, nil
+		}
+		return 200, map[string]string{"username": request.Principal.Username}, nil
+	})
+	router.GET("/whoami", whoami)
+	orders := router.POST("/orders", whoami)
+	orders.Scopes = []string{"orders/write"}
+	orders.Groups = []string{"admin", "ops"}
+	return newHandler(&processor{api: router.MustBuild()})
+}
+
+func TestRouteAuthorization(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		resource   string
+		authorizer map[string]interface{}
+		statusCode int
+		body       string
+	}{
+		{"anonymous open route", http.MethodGet, "/whoami", nil, 200, "anonymous"},
+		{"principal on open route", http.MethodGet, "/whoami", map[string]interface{}{"username": "user1"}, 200, "user1"},
+		{"authentication required", http.MethodPost, "/orders", nil, 401, "authentication required"},
+		{"scope missing", http.MethodPost, "/orders", map[string]interface{}{"username": "user1", "scopes": "orders/read", "groups": "admin"}, 403, "scope orders/write is required"},
+		{"group missing", http.MethodPost, "/orders", map[string]interface{}{"username": "user1", "scopes": "orders/write", "groups": "support"}, 403, "one of the groups admin, ops is required"},
+		{"authorized", http.MethodPost, "/orders", map[string]interface{}{"username": "user1", "scopes": "orders/write", "groups": "support,ops"}, 200, "user1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := events.APIGatewayProxyRequest{
+				HTTPMethod:     test.method,
+				Resource:       test.resource,
+				Path:           test.resource,
+				Headers:        map[string]string{"accept": "application/json"},
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: test.authorizer},
+			}
+			response, err := principalHandler().HandleAPIRequest(context.TODO(), request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStatus(t, response.StatusCode, test.statusCode)
+			if !strings.Contains(response.Body, test.body) {
+				t.Fatalf("body %v, expected %v", response.Body, test.body)
+			}
+		})
+	}
+}
+
+func TestHTTPAPIJWTAuthorizerScopes(t *testing.T) {
+	tests := []struct {
+		name       string
+		scopes     []string
+		statusCode int
+	}{
+		{"scope missing", []string{"orders/read"}, 403},
+		{"authorized", []string{"orders/write"}, 200},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httpAPIRequest("POST /orders", "/orders")
+			request.RequestContext.HTTP.Method = http.MethodPost
+			request.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+					Claims: map[string]string{"sub": "user-1", "username": "user1", "cognito:groups": "[admin]"},
+					Scopes: test.scopes,
+				},
+			}
+			response, err := principalHandler().HandleHTTPAPIRequest(context.TODO(), request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStatus(t, response.StatusCode, test.statusCode)
+		})
+	}
+}
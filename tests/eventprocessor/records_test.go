@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/utils"
+)
+
+func snsRecord(messageId, topic, message string) events.SNSEventRecord {
+	return events.SNSEventRecord{SNS: events.SNSEntity{
+		MessageID: messageId,
+		TopicArn:  "arn:aws:sns:ap-south-1:123456789012:" + topic,
+		Message:   message,
+	}}
+}
+
+func s3Record(eventName, bucket, key string) events.S3EventRecord {
+	record := events.S3EventRecord{EventName: eventName}
+	record.S3.Bucket.Name = bucket
+	record.S3.Object.Key = key
+	return record
+}
+
+// assertRecordsError checks the error reporting the failed records of a batch.
+func assertRecordsError(t *testing.T, err error, statusCode int, recordIds ...string) {
+	t.Helper()
+	custErr, ok := err.(*utils.Error)
+	if !ok || custErr.StatusCode != statusCode || custErr.ErrorCode != "RECORD_PROCESSING_FAILED" {
+		t.Fatalf("expected a %v records error, got %#v", statusCode, err)
+	}
+	recordErrors, ok := custErr.ErrorData.([]*eventprocessor.RecordError)
+	if !ok || len(recordErrors) != len(recordIds) {
+		t.Fatalf("record errors %#v, expected %v", custErr.ErrorData, recordIds)
+	}
+	for i, recordId := range recordIds {
+		if recordErrors[i].RecordId != recordId {
+			t.Fatalf("record errors %#v, expected %v", custErr.ErrorData, recordIds)
+		}
+	}
+}
+
+func TestSNSProcessesEveryRecord(t *testing.T) {
+	handled := make([]string, 0)
+	record := func(name string) eventprocessor.SNSHandler {
+		return func(payload interface{}) error {
+			handled = append(handled, name+":"+payload.(map[string]interface{})["id"].(string))
+			return nil
+		}
+	}
+	handler := newHandler(&processor{sns: map[string]map[string]*eventprocessor.SNS{
+		"dev_PAYMENT": {"transaction.failed": {SnsHandler: record("failed")}, "transaction.accepted": {SnsHandler: record("accepted")}},
+		"dev_ORDERS":  {"order.placed": {SnsHandler: record("placed")}},
+	}})
+	request := events.SNSEvent{Records: []events.SNSEventRecord{
+		snsRecord("1", "dev_PAYMENT", `{"event":"transaction.failed","id":"t1"}`),
+		snsRecord("2", "dev_ORDERS", `{"event":"order.placed","id":"o1"}`),
+		snsRecord("3", "dev_PAYMENT", `{"event":"transaction.accepted","id":"t2"}`),
+	}}
+	response, err := handler.HandleSNSRequest(context.TODO(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 204)
+	if !reflect.DeepEqual(handled, []string{"failed:t1", "placed:o1", "accepted:t2"}) {
+		t.Fatal("unexpected records handled", handled)
+	}
+}
+
+func TestSNSCollectsRecordErrors(t *testing.T) {
+	calls := 0
+	handler := newHandler(&processor{sns: map[string]map[string]*eventprocessor.SNS{
+		"dev_PAYMENT": {"transaction.failed": {SnsHandler: func(payload interface{}) error {
+			calls++
+			return nil
+		}}},
+	}})
+	request := events.SNSEvent{Records: []events.SNSEventRecord{
+		snsRecord("1", "dev_PAYMENT", `{"event":"transaction.failed"}`),
+		snsRecord("2", "dev_PAYMENT", `{"event":"transaction.refunded"}`),
+		snsRecord("3", "dev_UNKNOWN", `{"event":"transaction.failed"}`),
+		snsRecord("4", "dev_PAYMENT", `{"event":"transaction.failed"}`),
+	}}
+	_, err := handler.HandleSNSRequest(context.TODO(), request)
+	assertRecordsError(t, err, 404, "2", "3")
+	if calls != 2 {
+		t.Fatal("expected the other records to be handled, calls", calls)
+	}
+}
+
+func TestS3ProcessesEveryRecord(t *testing.T) {
+	handled := make([]string, 0)
+	trigger := func(name string) *eventprocessor.S3Trigger {
+		return &eventprocessor.S3Trigger{S3TriggerContextHandler: func(ctx context.Context, payload interface{}) error {
+			request := payload.(*events.S3Event)
+			handled = append(handled, name+":"+request.Records[0].S3.Object.URLDecodedKey)
+			return nil
+		}}
+	}
+	handler := newHandler(&processor{s3: map[string]map[string]map[string]*eventprocessor.S3Trigger{
+		"uploads":  {"ObjectCreated:Put": {"files/": trigger("files"), "files/reports/": trigger("reports")}},
+		"invoices": {"ObjectRemoved:Delete": {"": trigger("invoices")}},
+	}})
+	request := events.S3Event{Records: []events.S3EventRecord{
+		s3Record("ObjectCreated:Put", "uploads", "files/my+file%281%29.pdf"),
+		s3Record("ObjectCreated:Put", "uploads", "files/reports/q1.csv"),
+		s3Record("ObjectRemoved:Delete", "invoices", "2024/inv-1.pdf"),
+	}}
+	_, err := handler.HandleS3TriggerRequest(context.TODO(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"files:files/my file(1).pdf", "reports:files/reports/q1.csv", "invoices:2024/inv-1.pdf"}
+	if !reflect.DeepEqual(handled, expected) {
+		t.Fatalf("records handled %v, expected %v", handled, expected)
+	}
+}
+
+func TestS3CollectsRecordErrors(t *testing.T) {
+	handler := newHandler(&processor{s3: map[string]map[string]map[string]*eventprocessor.S3Trigger{
+		"uploads": {"ObjectCreated:Put": {"files/": {S3TriggerHandler: func(payload interface{}) error {
+			if payload.(*events.S3Event).Records[0].S3.Object.URLDecodedKey == "files/bad.pdf" {
+				return errHandler
+			}
+			return nil
+		}}}},
+	}})
+	request := events.S3Event{Records: []events.S3EventRecord{
+		s3Record("ObjectCreated:Put", "uploads", "files/good.pdf"),
+		s3Record("ObjectCreated:Put", "uploads", "files/bad.pdf"),
+		s3Record("ObjectCreated:Put", "uploads", "images/logo.png"),
+	}}
+	_, err := handler.HandleS3TriggerRequest(context.TODO(), request)
+	assertRecordsError(t, err, 500, "files/bad.pdf", "images/logo.png")
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+)
+
+const (
+	testBearerToken   = "Bearer eyJhbGciOiJSUzI1NiJ9.secret-token"
+	testSessionCookie = "session=secret-session-id"
+)
+
+func loggingRouter() map[string]map[string]*eventprocessor.API {
+	router := eventprocessor.NewRouter()
+	router.GET("/orders", func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+		return 200, map[string]string{"status": "ok"}, nil
+	})
+	return router.MustBuild()
+}
+
+func assertRedacted(t *testing.T, output string) {
+	t.Helper()
+	if strings.Contains(output, "secret-token") || strings.Contains(output, "secret-session-id") {
+		t.Fatal("credentials are logged in plain text", output)
+	}
+	if !strings.Contains(output, "<<<reduced>>>") {
+		t.Fatal("request is not logged", output)
+	}
+}
+
+func TestHTTPAPIRequestLoggingRedactsCredentials(t *testing.T) {
+	handler := newHandler(&processor{api: loggingRouter()})
+	request := events.APIGatewayV2HTTPRequest{
+		Version:  "2.0",
+		RouteKey: "GET /orders",
+		RawPath:  "/orders",
+		Headers: map[string]string{
+			"authorization": testBearerToken,
+			"accept":        "application/json",
+		},
+		Cookies: []string{testSessionCookie},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET", Path: "/orders"},
+		},
+	}
+	var response events.APIGatewayV2HTTPResponse
+	var err error
+	output := captureOutput(t, func() {
+		response, err = handler.HandleHTTPAPIRequest(context.TODO(), request)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 200)
+	assertRedacted(t, output)
+	if request.Headers["authorization"] != testBearerToken {
+		t.Fatal("request headers were modified", request.Headers)
+	}
+}
+
+func TestRequestLoggingRedactsErrorRequest(t *testing.T) {
+	handler := newHandler(&processor{api: loggingRouter()})
+	request := events.APIGatewayProxyRequest{
+		Resource:   "/missing",
+		Path:       "/missing",
+		HTTPMethod: "GET",
+		Headers:    map[string]string{"AUTHORIZATION": testBearerToken, "Cookie": testSessionCookie},
+	}
+	var response events.APIGatewayProxyResponse
+	output := captureOutput(t, func() {
+		response, _ = handler.HandleAPIRequest(context.TODO(), request)
+	})
+	assertStatus(t, response.StatusCode, 404)
+	assertRedacted(t, output)
+}
+
+func TestALBRequestLoggingRedactsCredentials(t *testing.T) {
+	handler := newHandler(&processor{api: loggingRouter()})
+	request := events.ALBTargetGroupRequest{
+		HTTPMethod: "GET",
+		Path:       "/orders",
+		MultiValueHeaders: map[string][]string{
+			"authorization": {testBearerToken},
+			"cookie":        {testSessionCookie},
+		},
+		MultiValueQueryStringParameters: map[string][]string{},
+		RequestContext: events.ALBTargetGroupRequestContext{
+			ELB: events.ELBContext{TargetGroupArn: "arn:aws:elasticloadbalancing:ap-south-1:123456789012:targetgroup/orders/1"},
+		},
+	}
+	var response events.ALBTargetGroupResponse
+	var err error
+	output := captureOutput(t, func() {
+		response, err = handler.HandleALBRequest(context.TODO(), request)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 200)
+	assertRedacted(t, output)
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+)
+
+func sqsMessages(queue string, ids ...string) events.SQSEvent {
+	request := events.SQSEvent{}
+	for _, id := range ids {
+		request.Records = append(request.Records, events.SQSMessage{
+			MessageId:      id,
+			Body:           id,
+			EventSourceARN: "arn:aws:sqs:ap-south-1:123456789012:dev_" + queue,
+		})
+	}
+	return request
+}
+
+func assertSQSFailures(t *testing.T, response events.SQSEventResponse, err error, messageIds ...string) {
+	t.Helper()
+	if err != nil {
+		t.Fatal("expected the failures to be reported per message, got", err)
+	}
+	if len(response.BatchItemFailures) != len(messageIds) {
+		t.Fatalf("batch item failures %+v, expected %v", response.BatchItemFailures, messageIds)
+	}
+	for i, messageId := range messageIds {
+		if response.BatchItemFailures[i].ItemIdentifier != messageId {
+			t.Fatalf("batch item failures %+v, expected %v", response.BatchItemFailures, messageIds)
+		}
+	}
+}
+
+func TestSQSBatchItemFailures(t *testing.T) {
+	processed := make([]string, 0)
+	handler := newHandler(&processor{sqs: map[string]*eventprocessor.SQS{
+		"ORDERS": {SQSRecordContextHandler: func(ctx context.Context, record *events.SQSMessage) error {
+			processed = append(processed, record.MessageId)
+			switch record.Body {
+			case "2":
+				return errHandler
+			case "3":
+				panic("message handler panic")
+			}
+			return nil
+		}},
+	}})
+	response, err := handler.HandleSQSBatchRequest(context.TODO(), sqsMessages("ORDERS", "1", "2", "3", "4"))
+	assertSQSFailures(t, response, err, "2", "3")
+	if len(processed) != 4 {
+		t.Fatal("expected every message to be processed, processed", processed)
+	}
+}
+
+func TestSQSBatchWithEventHandler(t *testing.T) {
+	batchSizes := make([]int, 0)
+	handler := newHandler(&processor{sqs: map[string]*eventprocessor.SQS{
+		"ORDERS": {SQSHandler: func(payload interface{}) error {
+			request := payload.(*events.SQSEvent)
+			batchSizes = append(batchSizes, len(request.Records))
+			if request.Records[0].Body == "1" {
+				return errHandler
+			}
+			return nil
+		}},
+	}})
+	response, err := handler.HandleSQSBatchRequest(context.TODO(), sqsMessages("ORDERS", "1", "2"))
+	assertSQSFailures(t, response, err, "1")
+	if len(batchSizes) != 2 || batchSizes[0] != 1 || batchSizes[1] != 1 {
+		t.Fatal("expected a single message event per message, got", batchSizes)
+	}
+}
+
+func TestSQSBatchUnknownQueue(t *testing.T) {
+	handler := newHandler(&processor{sqs: map[string]*eventprocessor.SQS{}})
+	response, err := handler.HandleSQSBatchRequest(context.TODO(), sqsMessages("ORDERS", "1", "2"))
+	assertSQSFailures(t, response, err, "1", "2")
+}
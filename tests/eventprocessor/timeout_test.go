@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/utils"
+)
+
+// timeoutContext leaves the handlers 100ms before the 500ms timeout margin.
+func timeoutContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.TODO(), 600*time.Millisecond)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func assertTimeout(t *testing.T, err error) {
+	t.Helper()
+	custErr, ok := err.(*utils.Error)
+	if !ok || custErr.StatusCode != 504 {
+		t.Fatal("expected a 504 error, got", err)
+	}
+}
+
+func TestAPITimeoutDoesNotShareTheRequest(t *testing.T) {
+	finished := make(chan struct{})
+	router := eventprocessor.NewRouter()
+	router.GET("/orders", func(ctx context.Context, headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+		defer close(finished)
+		<-ctx.Done()
+		requestHeaders := headers.(map[string]string)
+		for i := 0; i < 1000; i++ {
+			requestHeaders[fmt.Sprintf("X-Late-%v", i)] = "value"
+			eventprocessor.GetLogger(ctx).Debug("late handler log", i)
+		}
+		return 200, nil, nil
+	})
+	handler := newHandler(&processor{api: router.MustBuild()})
+	handler.SetTimeoutMargin(500 * time.Millisecond)
+	request := events.APIGatewayProxyRequest{
+		HTTPMethod: "GET",
+		Resource:   "/orders",
+		Path:       "/orders",
+		Headers:    map[string]string{"Accept": "application/json"},
+	}
+	response, err := handler.HandleAPIRequest(timeoutContext(t), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 504)
+	for key := range request.Headers {
+		if key != "Accept" {
+			t.Fatal("handler changed the request headers after the timeout", key)
+		}
+	}
+	<-finished
+}
+
+func TestSQSBatchTimeoutIsReturned(t *testing.T) {
+	handler := newHandler(&processor{sqs: map[string]*eventprocessor.SQS{
+		"ORDERS": {SQSContextHandler: func(ctx context.Context, payload interface{}) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	}})
+	handler.SetTimeoutMargin(500 * time.Millisecond)
+	request := events.SQSEvent{Records: []events.SQSMessage{{MessageId: "1", EventSourceARN: "arn:aws:sqs:ap-south-1:123456789012:dev_ORDERS"}}}
+	_, err := handler.HandleSQSBatchRequest(timeoutContext(t), request)
+	assertTimeout(t, err)
+}
+
+func TestSQSTimeoutIsReturned(t *testing.T) {
+	handler := newHandler(&processor{sqs: map[string]*eventprocessor.SQS{
+		"ORDERS": {SQSContextHandler: func(ctx context.Context, payload interface{}) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	}})
+	handler.SetTimeoutMargin(500 * time.Millisecond)
+	request := events.SQSEvent{Records: []events.SQSMessage{{MessageId: "1", EventSourceARN: "arn:aws:sqs:ap-south-1:123456789012:dev_ORDERS"}}}
+	_, err := handler.HandleSQSRequest(timeoutContext(t), request)
+	assertTimeout(t, err)
+}
+
+func TestALBPathParamsWithDeadline(t *testing.T) {
+	router := eventprocessor.NewRouter()
+	router.GET("/items/{id}", func(ctx context.Context, headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+		return 200, pathParam, nil
+	})
+	handler := newHandler(&processor{api: router.MustBuild()})
+	request := events.ALBTargetGroupRequest{
+		HTTPMethod:     "GET",
+		Path:           "/items/abc",
+		Headers:        map[string]string{"accept": "application/json"},
+		RequestContext: events.ALBTargetGroupRequestContext{ELB: events.ELBContext{TargetGroupArn: "arn:aws:elasticloadbalancing:ap-south-1:123456789012:targetgroup/items/1"}},
+	}
+	response, err := handler.HandleALBRequest(timeoutContext(t), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStatus(t, response.StatusCode, 200)
+	if strings.TrimSpace(response.Body) != `{"id":"abc"}` {
+		t.Fatal("unexpected body", response.Body)
+	}
+}
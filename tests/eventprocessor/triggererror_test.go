@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/utils"
+)
+
+var errHandler = errors.New("handler failed")
+
+func TestSNSHandlerErrorIsReturned(t *testing.T) {
+	handler := newHandler(&processor{sns: map[string]map[string]*eventprocessor.SNS{
+		"dev_PAYMENT": {"transaction.failed": {SnsHandler: func(payload interface{}) error { return errHandler }}},
+	}})
+	request := events.SNSEvent{Records: []events.SNSEventRecord{{SNS: events.SNSEntity{
+		MessageID: "1",
+		TopicArn:  "arn:aws:sns:ap-south-1:123456789012:dev_PAYMENT",
+		Message:   `{"event":"transaction.failed"}`,
+	}}}}
+	response, err := handler.HandleSNSRequest(context.TODO(), request)
+	if err != errHandler {
+		t.Fatal("expected the handler error, got", err)
+	}
+	assertStatus(t, response.StatusCode, 500)
+}
+
+func TestSNSUnmappedEventIsReturned(t *testing.T) {
+	handler := newHandler(&processor{sns: map[string]map[string]*eventprocessor.SNS{}})
+	request := events.SNSEvent{Records: []events.SNSEventRecord{{SNS: events.SNSEntity{
+		MessageID: "1",
+		TopicArn:  "arn:aws:sns:ap-south-1:123456789012:dev_PAYMENT",
+		Message:   `{"event":"transaction.failed"}`,
+	}}}}
+	_, err := handler.HandleSNSRequest(context.TODO(), request)
+	custErr, ok := err.(*utils.Error)
+	if !ok || custErr.StatusCode != 404 {
+		t.Fatal("expected a 404 error, got", err)
+	}
+}
+
+func TestSQSHandlerErrorIsReturned(t *testing.T) {
+	handler := newHandler(&processor{sqs: map[string]*eventprocessor.SQS{
+		"ORDERS": {SQSHandler: func(payload interface{}) error { return errHandler }},
+	}})
+	request := events.SQSEvent{Records: []events.SQSMessage{{MessageId: "1", EventSourceARN: "arn:aws:sqs:ap-south-1:123456789012:dev_ORDERS"}}}
+	_, err := handler.HandleSQSRequest(context.TODO(), request)
+	if err != errHandler {
+		t.Fatal("expected the handler error, got", err)
+	}
+}
+
+func TestSQSHandlerPanicIsReturned(t *testing.T) {
+	handler := newHandler(&processor{sqs: map[string]*eventprocessor.SQS{
+		"ORDERS": {SQSHandler: func(payload interface{}) error { panic("queue handler panic") }},
+	}})
+	request := events.SQSEvent{Records: []events.SQSMessage{{MessageId: "1", EventSourceARN: "arn:aws:sqs:ap-south-1:123456789012:dev_ORDERS"}}}
+	response, err := handler.HandleSQSRequest(context.TODO(), request)
+	if _, ok := err.(*eventprocessor.PanicError); !ok {
+		t.Fatal("expected a panic error, got", err)
+	}
+	assertStatus(t, response.StatusCode, 500)
+}
+
+func TestS3HandlerErrorIsReturned(t *testing.T) {
+	handler := newHandler(&processor{s3: map[string]map[string]map[string]*eventprocessor.S3Trigger{
+		"bucket": {"ObjectCreated:Put": {"uploads/": {S3TriggerHandler: func(payload interface{}) error { return errHandler }}}},
+	}})
+	record := events.S3EventRecord{EventName: "ObjectCreated:Put"}
+	record.S3.Bucket.Name = "bucket"
+	record.S3.Object.Key = "uploads/file.pdf"
+	_, err := handler.HandleS3TriggerRequest(context.TODO(), events.S3Event{Records: []events.S3EventRecord{record}})
+	if err != errHandler {
+		t.Fatal("expected the handler error, got", err)
+	}
+}
+
+func TestCronHandlerErrorIsReturned(t *testing.T) {
+	handler := newHandler(&processor{cron: map[string]*eventprocessor.CronInvocation{
+		"NIGHTLY": {CronHandlerFunc: func(payload interface{}) (int, interface{}, error) { return 0, nil, errHandler }},
+	}})
+	_, err := handler.HandleCronInvocation(context.TODO(), eventprocessor.CronEvent{IsCron: true, ActionName: "NIGHTLY"})
+	if err != errHandler {
+		t.Fatal("expected the handler error, got", err)
+	}
+}
+
+func TestAPIHandlerErrorIsMapped(t *testing.T) {
+	router := eventprocessor.NewRouter()
+	router.GET("/orders", func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+		return 0, nil, utils.NewError(409, "order is locked", "ORDER_LOCKED", nil)
+	})
+	handler := newHandler(&processor{api: router.MustBuild()})
+	response, err := handler.HandleAPIRequest(context.TODO(), events.APIGatewayProxyRequest{Resource: "/orders", Path: "/orders", HTTPMethod: "GET"})
+	if err != nil {
+		t.Fatal("API errors are mapped to the response, got", err)
+	}
+	assertStatus(t, response.StatusCode, 409)
+}
+
+func TestEventBridgeHandlerErrorIsReturned(t *testing.T) {
+	handler := newHandler(&processor{eventBridge: map[string]map[string][]*eventprocessor.EventBridge{
+		"orders": {"Order Placed": {{EventBridgeHandler: func(ctx context.Context, event *events.CloudWatchEvent, detail interface{}) error { return errHandler }}}},
+	}})
+	request := events.CloudWatchEvent{Source: "orders", DetailType: "Order Placed", Detail: []byte(`{"orderId":"1"}`)}
+	_, err := handler.HandleEventBridgeRequest(context.TODO(), request)
+	if err != errHandler {
+		t.Fatal("expected the handler error, got", err)
+	}
+	request.DetailType = "Order Cancelled"
+	_, err = handler.HandleEventBridgeRequest(context.TODO(), request)
+	custErr, ok := err.(*utils.Error)
+	if !ok || custErr.StatusCode != 404 {
+		t.Fatal("expected a 404 error for the unmapped event, got", err)
+	}
+}
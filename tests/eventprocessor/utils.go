@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/log"
+)
+
+// processor is a stub EventProcessor returning the handler maps set by the test.
+type processor struct {
+	api         map[string]map[string]*eventprocessor.API
+	sns         map[string]map[string]*eventprocessor.SNS
+	cron        map[string]*eventprocessor.CronInvocation
+	lambda      map[string]*eventprocessor.LambdaInvocation
+	sqs         map[string]*eventprocessor.SQS
+	s3          map[string]map[string]map[string]*eventprocessor.S3Trigger
+	eventBridge map[string]map[string][]*eventprocessor.EventBridge
+	dynamoDB    map[string]map[string]*eventprocessor.DynamoDBStream
+}
+
+func (p *processor) GetAPIHandler() map[string]map[string]*eventprocessor.API { return p.api }
+
+func (p *processor) GetSNSHandler() map[string]map[string]*eventprocessor.SNS { return p.sns }
+
+func (p *processor) GetCronHandler() map[string]*eventprocessor.CronInvocation { return p.cron }
+
+func (p *processor) GetLambdaHandler() map[string]*eventprocessor.LambdaInvocation { return p.lambda }
+
+func (p *processor) GetSQSEventHandler() map[string]*eventprocessor.SQS { return p.sqs }
+
+func (p *processor) GetS3EventHandler() map[string]map[string]map[string]*eventprocessor.S3Trigger {
+	return p.s3
+}
+
+func (p *processor) GetEventBridgeHandler() map[string]map[string][]*eventprocessor.EventBridge {
+	return p.eventBridge
+}
+
+func (p *processor) GetDynamoDBStreamHandler() map[string]map[string]*eventprocessor.DynamoDBStream {
+	return p.dynamoDB
+}
+
+func newHandler(p *processor) *eventprocessor.Handler {
+	return eventprocessor.GetHandler(false, func(ctx context.Context, logger *log.Log, event interface{}, eventType eventprocessor.EventType) eventprocessor.EventProcessor {
+		return p
+	})
+}
+
+func assertStatus(t *testing.T, statusCode, expected int) {
+	t.Helper()
+	if statusCode != expected {
+		t.Fatalf("status code %v, expected %v", statusCode, expected)
+	}
+}
+
+// captureOutput returns what fn printed to stdout, the local log printer writes there.
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	output := make(chan string)
+	go func() {
+		blob, _ := io.ReadAll(reader)
+		output <- string(blob)
+	}()
+	defer func() {
+		os.Stdout = stdout
+	}()
+	fn()
+	writer.Close()
+	return <-output
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"gobase-lambda/eventprocessor"
+)
+
+type address struct {
+	City    string `json:"city" validate:"required"`
+	Pincode string `json:"pincode" validate:"len=6,regex=^[0-9]+$"`
+}
+
+type customer struct {
+	Name      string    `json:"name" validate:"required,min=3,max=10"`
+	Age       int       `json:"age" validate:"min=18,max=60"`
+	Score     float64   `json:"score" validate:"max=9.5"`
+	PAN       string    `json:"pan" validate:"len=10,regex=^[A-Z]{5}[0-9]{4}[A-Z]$"`
+	Gender    string    `json:"gender" validate:"enum=M|F|O"`
+	Channels  []string  `json:"channels" validate:"max=2,enum=web|app"`
+	Email     *string   `json:"email" validate:"email"`
+	Address   *address  `json:"address" validate:"required"`
+	Addresses []address `json:"addresses"`
+	Internal  string    `json:"-" validate:"required"`
+}
+
+func validCustomer() *customer {
+	email := "akshay@example.com"
+	return &customer{
+		Name:      "akshay",
+		Age:       30,
+		Score:     9.5,
+		PAN:       "ABCDE1234F",
+		Gender:    "M",
+		Channels:  []string{"web"},
+		Email:     &email,
+		Address:   &address{City: "Pune", Pincode: "411001"},
+		Addresses: []address{{City: "Mumbai", Pincode: "400001"}},
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	invalidEmail := "akshay@"
+	tests := []struct {
+		name   string
+		change func(c *customer)
+		field  string
+		reason string
+	}{
+		{"valid", func(c *customer) {}, "", ""},
+		{"required", func(c *customer) { c.Name = "" }, "name", "is required"},
+		{"min length", func(c *customer) { c.Name = "ak" }, "name", "length must be at least 3"},
+		{"max length", func(c *customer) { c.Name = "akshaykanawat" }, "name", "length must be at most 10"},
+		{"min", func(c *customer) { c.Age = 17 }, "age", "must be at least 18"},
+		{"max", func(c *customer) { c.Age = 61 }, "age", "must be at most 60"},
+		{"max float", func(c *customer) { c.Score = 9.6 }, "score", "must be at most 9.5"},
+		{"len", func(c *customer) { c.PAN = "ABCDE1234" }, "pan", "length must be 10"},
+		{"regex", func(c *customer) { c.PAN = "abcde1234f" }, "pan", "must match ^[A-Z]{5}[0-9]{4}[A-Z]$"},
+		{"enum", func(c *customer) { c.Gender = "X" }, "gender", "must be one of M, F, O"},
+		{"slice max", func(c *customer) { c.Channels = []string{"web", "app", "web"} }, "channels", "length must be at most 2"},
+		{"slice enum", func(c *customer) { c.Channels = []string{"web", "sms"} }, "channels", "must be one of web, app"},
+		{"email", func(c *customer) { c.Email = &invalidEmail }, "email", "must be a valid email address"},
+		{"nil pointer is only required", func(c *customer) { c.Email = nil }, "", ""},
+		{"empty string is only required", func(c *customer) { c.Gender = "" }, "", ""},
+		{"required struct", func(c *customer) { c.Address = nil }, "address", "is required"},
+		{"nested", func(c *customer) { c.Address.City = "" }, "address.city", "is required"},
+		{"nested regex", func(c *customer) { c.Address.Pincode = "41100A" }, "address.pincode", "must match ^[0-9]+$"},
+		{"slice of structs", func(c *customer) { c.Addresses = append(c.Addresses, address{City: "Delhi", Pincode: "1100"}) }, "addresses[1].pincode", "length must be 6"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value := validCustomer()
+			test.change(value)
+			fieldErrors := eventprocessor.Validate(value, "body")
+			if test.field == "" {
+				if len(fieldErrors) > 0 {
+					t.Fatalf("unexpected field error %+v", fieldErrors[0])
+				}
+				return
+			}
+			if len(fieldErrors) != 1 {
+				t.Fatalf("expected a single field error, got %v", len(fieldErrors))
+			}
+			if fieldError := fieldErrors[0]; fieldError.Field != test.field || fieldError.In != "body" || fieldError.Reason != test.reason {
+				t.Fatalf("unexpected field error %+v", fieldError)
+			}
+		})
+	}
+}
+
+func TestInvalidValidateTagsFailOnBuild(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    interface{}
+		problem string
+	}{
+		{"unknown rule", &struct {
+			Name string `json:"name" validate:"required,alpha"`
+		}{}, "unknown validation rule alpha"},
+		{"malformed regex", &struct {
+			Name string `json:"name" validate:"regex=^[A-Z"`
+		}{}, "invalid validation regex"},
+		{"invalid bound", &struct {
+			Age int `json:"age" validate:"min=ten"`
+		}{}, "invalid validation bound ten"},
+		{"invalid length", &struct {
+			Name string `json:"name" validate:"len=six"`
+		}{}, "invalid validation length six"},
+		{"nested", &struct {
+			Items []struct {
+				SKU string `json:"sku" validate:"uuid"`
+			} `json:"items"`
+		}{}, "unknown validation rule uuid"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			apiMap := map[string]map[string]*eventprocessor.API{"/orders": {"POST": {
+				Body: test.body,
+				ApiHandler: func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+					return 200, nil, nil
+				},
+			}}}
+			err := eventprocessor.ValidateRoutes(apiMap)
+			if err == nil || !strings.Contains(err.Error(), test.problem) {
+				t.Fatalf("expected %q, got %v", test.problem, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+)
+
+func TestALBManagerGET(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	request := events.ALBTargetGroupRequest{
+		HTTPMethod: "GET",
+		Path:       "/cust_fasdfafsdf",
+		MultiValueQueryStringParameters: map[string][]string{
+			"name":   {"hello%20world"},
+			"status": {"test", "test2"},
+		},
+		MultiValueHeaders: map[string][]string{
+			"accept": {"application/json"},
+		},
+		RequestContext: events.ALBTargetGroupRequestContext{
+			ELB: events.ELBContext{TargetGroupArn: "arn:aws:elasticloadbalancing:ap-south-1:490302598154:targetgroup/gobase/fasdfasdf"},
+		},
+	}
+	response, err := handler.HandleALBRequest(context.TODO(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.MultiValueHeaders == nil {
+		t.Fatal("multi value headers expected in response")
+	}
+	fmt.Printf("%+v\n", response)
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCSVCodec(t *testing.T) {
+	codec, ok := eventprocessor.GetCodec("text/csv")
+	if !ok {
+		t.Fatal("csv codec is not registered")
+	}
+	payloads := make([]example.Payload, 0)
+	err := codec.Decode([]byte("Name,Gender\nakshay,M\nmeera,F\n"), &payloads)
+	fmt.Printf("%+v\n", payloads)
+	if err != nil || len(payloads) != 2 {
+		t.Fatal(err)
+	}
+	blob, err := codec.Encode([]example.QueryParams{{Name: "akshay", Status: []string{"A", "B"}, Age: 30}})
+	fmt.Println(string(blob), err)
+}
+
+func TestMsgpackCodec(t *testing.T) {
+	codec, _ := eventprocessor.GetCodec("application/msgpack")
+	blob, err := codec.Encode(example.QueryParams{Name: "akshay", NumList: []int{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queryParams := example.QueryParams{}
+	err = codec.Decode(blob, &queryParams)
+	fmt.Printf("%+v\n", queryParams)
+	if err != nil || queryParams.Name != "akshay" {
+		t.Fatal(err)
+	}
+}
+
+func TestProtobufCodec(t *testing.T) {
+	codec, _ := eventprocessor.GetCodec("application/x-protobuf")
+	blob, err := codec.Encode(wrapperspb.String("akshay"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := &wrapperspb.StringValue{}
+	err = codec.Decode(blob, message)
+	fmt.Println(message.GetValue(), err)
+	jsonCodec, _ := eventprocessor.GetCodec("application/json")
+	jsonBlob, err := jsonCodec.Encode(message)
+	fmt.Println(string(jsonBlob), err)
+	if message.GetValue() != "akshay" {
+		t.Fatal("unexpected message")
+	}
+}
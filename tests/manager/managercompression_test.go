@@ -0,0 +1,32 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+)
+
+func TestAPIManagerCompression(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	handler.SetCompression(&eventprocessor.CompressionConfig{MinSize: 64})
+	request := events.APIGatewayProxyRequest{
+		Resource:   "/{customerId}",
+		HTTPMethod: "GET",
+		Headers: map[string]string{
+			"Accept-Encoding": "gzip, deflate, br",
+		},
+		PathParameters: map[string]string{
+			"customerId": "cust_fasdfafsdf",
+		},
+	}
+	response, err := handler.HandleAPIRequest(context.TODO(), request)
+	fmt.Printf("%+v\n", response)
+	fmt.Println(err)
+	if response.Headers["Vary"] != "Accept-Encoding" {
+		t.Fatal("expected the response to vary by Accept-Encoding")
+	}
+}
@@ -0,0 +1,24 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+)
+
+func TestCronManagerContextAction(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	handler.SetTimeoutMargin(time.Second)
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancel()
+	response, err := handler.HandleCronInvocation(ctx, eventprocessor.CronEvent{IsCron: true, ActionName: "CRON_ACTION_2", Payload: map[string]string{"hello": "world"}})
+	fmt.Printf("%+v\n", response)
+	fmt.Println(err)
+	if response.StatusCode != 200 {
+		t.Fatal("unexpected status code", response.StatusCode)
+	}
+}
@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+)
+
+func TestAPIManagerPreflight(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	handler.SetCORS(&eventprocessor.CORSConfig{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	})
+	request := events.APIGatewayProxyRequest{
+		Resource:   "/{customerId}",
+		Path:       "/cust_fasdfafsdf",
+		HTTPMethod: "OPTIONS",
+		Headers: map[string]string{
+			"Origin":                         "https://app.example.com",
+			"Access-Control-Request-Method":  "POST",
+			"Access-Control-Request-Headers": "Content-Type",
+		},
+	}
+	response, err := handler.HandleAPIRequest(context.TODO(), request)
+	fmt.Printf("%+v\n", response)
+	fmt.Println(err)
+	if response.StatusCode != 204 || response.Headers["Access-Control-Allow-Origin"] != "https://app.example.com" {
+		t.Fatal("expected the preflight to be answered")
+	}
+}
@@ -19,3 +19,16 @@ func TestCronManagerActionOne(t *testing.T) {
 	fmt.Println(response)
 	fmt.Println(err)
 }
+
+func TestCronManagerScheduledEvent(t *testing.T) {
+	var LambdaEvent eventprocessor.CronEvent
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	jsonStr := "{\"version\":\"0\",\"id\":\"53dc4d37-cffa-4f76-80c9-8b7d4a4d2eaa\",\"detail-type\":\"Scheduled Event\",\"source\":\"aws.events\",\"account\":\"123456789012\",\"time\":\"2026-10-18T00:00:00Z\",\"region\":\"ap-south-1\",\"resources\":[\"arn:aws:events:ap-south-1:123456789012:rule/CRON_ACTION_2\"],\"detail\":{}}"
+	json.Unmarshal([]byte(jsonStr), &LambdaEvent)
+	response, err := handler.HandleCronInvocation(context.TODO(), LambdaEvent)
+	fmt.Println(response)
+	fmt.Println(err)
+	if response.StatusCode != 200 {
+		t.Fatal("unexpected status code", response.StatusCode)
+	}
+}
@@ -0,0 +1,25 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+)
+
+func TestDynamoDBManagerCustomerChanged(t *testing.T) {
+	var request events.DynamoDBEvent
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	jsonStr := "{\"Records\":[{\"eventID\":\"1\",\"eventName\":\"MODIFY\",\"eventSource\":\"aws:dynamodb\",\"eventSourceARN\":\"arn:aws:dynamodb:ap-south-1:123456789012:table/dev_CUSTOMER/stream/2026-10-18T00:00:00.000\",\"dynamodb\":{\"SequenceNumber\":\"111\",\"Keys\":{\"customerId\":{\"S\":\"cust_1\"}},\"NewImage\":{\"customerId\":{\"S\":\"cust_1\"},\"name\":{\"S\":\"New Name\"},\"tags\":{\"SS\":[\"gold\"]}},\"OldImage\":{\"customerId\":{\"S\":\"cust_1\"},\"name\":{\"S\":\"Old Name\"}}}},{\"eventID\":\"2\",\"eventName\":\"REMOVE\",\"eventSource\":\"aws:dynamodb\",\"eventSourceARN\":\"arn:aws:dynamodb:ap-south-1:123456789012:table/dev_CUSTOMER/stream/2026-10-18T00:00:00.000\",\"dynamodb\":{\"SequenceNumber\":\"112\",\"Keys\":{\"customerId\":{\"S\":\"cust_1\"}},\"OldImage\":{\"customerId\":{\"S\":\"cust_1\"},\"name\":{\"S\":\"New Name\"}}}}]}"
+	json.Unmarshal([]byte(jsonStr), &request)
+	response, err := handler.HandleDynamoDBStreamRequest(context.TODO(), request)
+	fmt.Printf("%+v\n", response)
+	fmt.Println(err)
+	if err != nil || len(response.BatchItemFailures) != 0 {
+		t.Fatal("unexpected batch item failures", response.BatchItemFailures)
+	}
+}
@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+)
+
+func TestEventManagerSNS(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	payload_json, err := ioutil.ReadFile("samples/sample_sns_transaction_accepted.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := handler.HandleEvent(context.TODO(), payload_json)
+	fmt.Println(response)
+	fmt.Println(err)
+}
+
+func TestEventManagerLambda(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	jsonStr := "{\"isLambdaInvocation\":true,\"actionName\":\"ACTION_1\",\"payload\":{\"hello\":\"world\"}}"
+	response, err := handler.HandleEvent(context.TODO(), []byte(jsonStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, ok := response.(map[string]interface{})
+	if !ok || next["actionName"] != "ACTION_2" {
+		t.Fatalf("unexpected response %#v", response)
+	}
+}
@@ -0,0 +1,40 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+	"gobase-lambda/utils"
+)
+
+func TestEventBridgeManagerTransactionUpdated(t *testing.T) {
+	var request events.CloudWatchEvent
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	jsonStr := "{\"version\":\"0\",\"id\":\"6a7e8feb-b491-4cf7-a9f1-bf3703467718\",\"detail-type\":\"Transaction Updated\",\"source\":\"mfcore.payment\",\"account\":\"123456789012\",\"time\":\"2026-10-18T00:00:00Z\",\"region\":\"ap-south-1\",\"resources\":[],\"detail\":{\"transactionId\":\"TXN001\",\"status\":\"REJECTED\",\"amount\":100}}"
+	json.Unmarshal([]byte(jsonStr), &request)
+	response, err := handler.HandleEventBridgeRequest(context.TODO(), request)
+	fmt.Println(response)
+	fmt.Println(err)
+	if err != nil || response.StatusCode != 204 {
+		t.Fatal("unexpected status code", response.StatusCode)
+	}
+}
+
+func TestEventBridgeManagerInvalidDetail(t *testing.T) {
+	var request events.CloudWatchEvent
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	jsonStr := "{\"version\":\"0\",\"id\":\"6a7e8feb-b491-4cf7-a9f1-bf3703467719\",\"detail-type\":\"Transaction Updated\",\"source\":\"mfcore.payment\",\"detail\":{\"status\":\"UNKNOWN\"}}"
+	json.Unmarshal([]byte(jsonStr), &request)
+	response, err := handler.HandleEventBridgeRequest(context.TODO(), request)
+	fmt.Println(response)
+	fmt.Println(err)
+	custErr, ok := err.(*utils.Error)
+	if !ok || custErr.StatusCode != 400 || custErr.ErrorCode != "VALIDATION_FAILED" {
+		t.Fatal("expected a validation error, got", err)
+	}
+}
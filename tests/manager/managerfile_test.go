@@ -0,0 +1,29 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+)
+
+func TestAPIManagerDownloadFile(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	request := events.APIGatewayProxyRequest{
+		Resource:   "/download/file",
+		HTTPMethod: "POST",
+		Headers: map[string]string{
+			"Accept": "application/pdf",
+		},
+		Body: `{"fileName": "dev/temp/gobasetest/statement.pdf"}`,
+	}
+	response, err := handler.HandleAPIRequest(context.TODO(), request)
+	fmt.Printf("%+v\n", response.Headers)
+	fmt.Println(err)
+	if response.StatusCode == 200 && !response.IsBase64Encoded {
+		t.Fatal("expected a base64 encoded body")
+	}
+}
@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+)
+
+func TestHTTPAPIManagerGET(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	request := events.APIGatewayV2HTTPRequest{
+		Version:        "2.0",
+		RouteKey:       "GET /{customerId}",
+		RawPath:        "/cust_fasdfafsdf",
+		RawQueryString: "name=hello&status=test&status=test2&numList=1&numList=2&flag=true",
+		Cookies:        []string{"session=fasdfasdf"},
+		PathParameters: map[string]string{
+			"customerId": "cust_fasdfafsdf",
+		},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey: "GET /{customerId}",
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method: "GET",
+				Path:   "/cust_fasdfafsdf",
+			},
+		},
+	}
+	response, err := handler.HandleHTTPAPIRequest(context.TODO(), request)
+	fmt.Printf("%+v\n", response)
+	fmt.Println(err)
+}
+
+func TestFunctionURLManagerGET(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	request := events.LambdaFunctionURLRequest{
+		Version:        "2.0",
+		RawPath:        "/cust_fasdfafsdf",
+		RawQueryString: "name=hello",
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			DomainName: "fasdfasdf.lambda-url.ap-south-1.on.aws",
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{
+				Method: "GET",
+				Path:   "/cust_fasdfafsdf",
+			},
+		},
+	}
+	response, err := handler.HandleFunctionURLRequest(context.TODO(), request)
+	fmt.Printf("%+v\n", response)
+	fmt.Println(err)
+}
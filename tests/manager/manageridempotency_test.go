@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/eventprocessor/idempotency"
+	"gobase-lambda/example"
+)
+
+func TestAPIManagerIdempotentPOST(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	body := map[string]interface{}{
+		"name":   "Sabariram",
+		"gender": "M",
+	}
+	bodyBlob, _ := json.Marshal(body)
+	request := events.APIGatewayProxyRequest{
+		Resource:   "/{customerId}",
+		HTTPMethod: "POST",
+		Body:       string(bodyBlob),
+		Headers: map[string]string{
+			idempotency.HeaderIdempotencyKey: uuid.NewString(),
+		},
+		PathParameters: map[string]string{
+			"customerId": "cust_fasdfafsdf",
+		},
+	}
+	response, err := handler.HandleAPIRequest(context.TODO(), request)
+	fmt.Printf("%+v\n", response)
+	fmt.Println(err)
+	replayed, err := handler.HandleAPIRequest(context.TODO(), request)
+	fmt.Printf("%+v\n", replayed)
+	fmt.Println(err)
+	if response.StatusCode == 200 && (replayed.Body != response.Body || replayed.Headers[idempotency.HeaderReplayed] != "true") {
+		t.Fatal("expected the response to be replayed")
+	}
+}
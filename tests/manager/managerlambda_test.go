@@ -3,7 +3,7 @@ package tests
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"reflect"
 	"testing"
 
 	"gobase-lambda/eventprocessor"
@@ -14,18 +14,28 @@ func TestLambdaManagerActionOne(t *testing.T) {
 	var LambdaEvent eventprocessor.LambdaEvent
 	handler := eventprocessor.GetHandler(false, example.NewManager)
 	jsonStr := "{\"isLambdaInvocation\":true,\"actionName\":\"ACTION_1\",\"payload\":{\"hello\":\"world\"}}"
-	json.Unmarshal([]byte(jsonStr), &LambdaEvent)
+	if err := json.Unmarshal([]byte(jsonStr), &LambdaEvent); err != nil {
+		t.Fatal(err)
+	}
 	response, err := handler.HandleLambdaInvocation(context.TODO(), LambdaEvent)
-	fmt.Println(response)
-	fmt.Println(err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the response is the next LambdaEvent, like a Step Functions state output
+	next, ok := response.(map[string]interface{})
+	if !ok || next["actionName"] != "ACTION_2" || next["isLambdaInvocation"] != true {
+		t.Fatalf("unexpected response %#v", response)
+	}
 }
 
 func TestLambdaManagerActionTwo(t *testing.T) {
-	// var LambdaEvent eventprocessor.LambdaEvent
 	handler := eventprocessor.GetHandler(false, example.NewManager)
 	jsonStr := "{\"isLambdaInvocation\":true,\"actionName\":\"ACTION_2\",\"payload\":{\"hello\":\"worlssd\"}}"
-	// json.Unmarshal([]byte(jsonStr), &LambdaEvent)
 	response, err := handler.HandleLambdaInvocation(context.TODO(), jsonStr)
-	fmt.Println(response)
-	fmt.Println(err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(response, map[string]string{"ACTION": "TWO"}) {
+		t.Fatalf("unexpected response %#v", response)
+	}
 }
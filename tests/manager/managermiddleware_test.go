@@ -0,0 +1,29 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+)
+
+func TestManagerMiddleware(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	invoked := make([]string, 0)
+	handler.Use(eventprocessor.MiddlewareFunc(func(invocation *eventprocessor.Invocation, next eventprocessor.Next) (interface{}, error) {
+		invoked = append(invoked, "global")
+		return next(invocation)
+	}))
+	handler.UseFor(eventprocessor.EventCRON, eventprocessor.MiddlewareFunc(func(invocation *eventprocessor.Invocation, next eventprocessor.Next) (interface{}, error) {
+		invoked = append(invoked, "cron")
+		panic("unexpected failure")
+	}))
+	response, err := handler.HandleCronInvocation(context.TODO(), eventprocessor.CronEvent{IsCron: true, ActionName: "ACTION_1"})
+	fmt.Printf("%+v\n", response)
+	fmt.Println(invoked, err)
+	if response.StatusCode != 500 || len(invoked) != 2 {
+		t.Fatal("expected the panic of the cron middleware to be recovered")
+	}
+}
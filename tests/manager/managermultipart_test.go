@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"testing"
+
+	"gobase-lambda/eventprocessor"
+)
+
+func TestParseMultipartForm(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("fileName", "statement.pdf")
+	fileWriter, _ := writer.CreateFormFile("file", "statement.pdf")
+	fileWriter.Write([]byte("%PDF-1.4 statement"))
+	writer.Close()
+	form, err := eventprocessor.ParseMultipartForm(writer.FormDataContentType(), body.Bytes(), &eventprocessor.MultipartLimits{
+		MaxFileSize:  1024,
+		AllowedTypes: []string{"application/pdf"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := form.File("file")
+	fmt.Printf("%+v %+v\n", form.Values, file)
+	if form.Value("fileName") != "statement.pdf" || file.ContentType != "application/pdf" {
+		t.Fatal("unexpected form")
+	}
+	_, err = eventprocessor.ParseMultipartForm(writer.FormDataContentType(), body.Bytes(), &eventprocessor.MultipartLimits{
+		AllowedTypes: []string{"image/*"},
+	})
+	fmt.Println(err)
+	if err == nil {
+		t.Fatal("expected the file type to be rejected")
+	}
+}
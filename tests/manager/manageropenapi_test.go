@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+)
+
+func TestOpenAPI(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	document := handler.OpenAPI(eventprocessor.OpenAPIInfo{Title: "gobase example API", Version: "1.0.0"})
+	blob, _ := json.MarshalIndent(document, "", "  ")
+	fmt.Println(string(blob))
+	operation := document.Paths["/{customerId}"]["get"]
+	if operation == nil || len(operation.Parameters) == 0 || operation.Parameters[0].In != "path" {
+		t.Fatal("expected the customerId path parameter")
+	}
+	if document.Components.Schemas["Payload"] == nil {
+		t.Fatal("expected the Payload schema")
+	}
+}
+
+func TestAPIManagerOpenAPIRoute(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	handler.ServeOpenAPI(eventprocessor.OpenAPIInfo{Title: "gobase example API", Version: "1.0.0"})
+	request := events.APIGatewayProxyRequest{
+		Resource:   eventprocessor.OpenAPIResource,
+		HTTPMethod: "GET",
+	}
+	response, err := handler.HandleAPIRequest(context.TODO(), request)
+	fmt.Printf("%+v\n", response)
+	fmt.Println(err)
+	if response.StatusCode != 200 {
+		t.Fatal("expected the OpenAPI document")
+	}
+}
@@ -0,0 +1,30 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+)
+
+func TestCognitoPrincipal(t *testing.T) {
+	request := events.APIGatewayProxyRequest{
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Authorizer: map[string]interface{}{
+				"claims": map[string]interface{}{
+					"iss":              "https://cognito-idp.ap-south-1.amazonaws.com/ap-south-1_fasdfasdf",
+					"sub":              "5f3c8f2e-0c1b-4c59-a3f0-2f5e1b0c7d11",
+					"cognito:username": "akshay",
+					"cognito:groups":   "[admin ops]",
+					"scope":            "customer/read customer/write",
+				},
+			},
+		},
+	}
+	principal := eventprocessor.GetPrincipal(&request)
+	fmt.Printf("%+v\n", principal)
+	if principal.Source != eventprocessor.PrincipalSourceCognito || !principal.InGroup("ops") || !principal.HasScope("customer/read") {
+		t.Fatal("unexpected principal")
+	}
+}
@@ -0,0 +1,34 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+)
+
+func TestAPIManagerProblem(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	request := events.APIGatewayProxyRequest{
+		Resource:   "/not-found",
+		Path:       "/not-found",
+		HTTPMethod: "GET",
+		Headers: map[string]string{
+			"x-correlation-id": "test-correlation-id",
+		},
+	}
+	response, err := handler.HandleAPIRequest(context.TODO(), request)
+	fmt.Printf("%+v\n", response)
+	fmt.Println(err)
+	problem := &eventprocessor.Problem{}
+	if err := json.Unmarshal([]byte(response.Body), problem); err != nil {
+		t.Fatal(err)
+	}
+	if response.Headers["Content-Type"] != eventprocessor.MediaTypeProblem || problem.Status != 404 || problem.CorrelationId != "test-correlation-id" {
+		t.Fatal("unexpected problem", problem)
+	}
+}
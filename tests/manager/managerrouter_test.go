@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+	"gobase-lambda/log"
+)
+
+func TestManagerRoutes(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	err := handler.ValidateRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManagerRoutesAreBuiltOnce(t *testing.T) {
+	routes := example.NewManager(context.TODO(), log.GetDefaultLogger(), nil, eventprocessor.EventAPI).GetAPIHandler()
+	other := example.NewManager(context.TODO(), log.GetDefaultLogger(), nil, eventprocessor.EventAPI).GetAPIHandler()
+	if routes["/{customerId}"]["GET"] != other["/{customerId}"]["GET"] {
+		t.Fatal("expected the routes to be built once per process")
+	}
+}
+
+type invalidPathParams struct {
+	OrderId string `json:"orderId"`
+}
+
+func TestRouterInvalidRoutes(t *testing.T) {
+	get := func(headers interface{}, pathParam interface{}, jsonBody string, queryParams interface{}) (int, interface{}, error) {
+		return 200, nil, nil
+	}
+	r := eventprocessor.NewRouter()
+	r.GET("/{customerId}", get)
+	r.GET("/{id}", get)
+	r.GET("/orders/{orderId", get)
+	r.GET("/files/{path+}/meta", get)
+	r.POST("/orders", &eventprocessor.API{})
+	r.GET("/customers/{customerId}", &eventprocessor.API{ApiHandler: get, PathParams: &invalidPathParams{}})
+	_, err := r.Build()
+	fmt.Println(err)
+	if err == nil {
+		t.Fatal("expected invalid routes")
+	}
+}
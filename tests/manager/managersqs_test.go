@@ -0,0 +1,30 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"gobase-lambda/eventprocessor"
+	"gobase-lambda/example"
+)
+
+func TestSQSManagerBatchItemFailures(t *testing.T) {
+	handler := eventprocessor.GetHandler(false, example.NewManager)
+	queueArn := fmt.Sprintf("arn:aws:sqs:ap-south-1:490302598154:%s_TEST_BATCH_QUEUE", os.Getenv("stage"))
+	request := events.SQSEvent{
+		Records: []events.SQSMessage{
+			{MessageId: "msg-1", Body: `{"hello":"world"}`, EventSourceARN: queueArn},
+			{MessageId: "msg-2", Body: "", EventSourceARN: queueArn},
+		},
+	}
+	response, err := handler.HandleSQSBatchRequest(context.TODO(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.BatchItemFailures) != 1 || response.BatchItemFailures[0].ItemIdentifier != "msg-2" {
+		t.Fatalf("unexpected batch item failures %+v", response.BatchItemFailures)
+	}
+}